@@ -0,0 +1,45 @@
+// errdoc generates the markdown table of error codes of ierror catalog files for the API documentation.
+//
+//	go run ./errdoc -o errors.md errors.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"goplate/pkg/ierror"
+	"io"
+	"os"
+)
+
+func main() {
+	output := flag.String("o", "", "output file, stdout when empty")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: errdoc [-o output.md] catalog.yaml [catalog.json ...]")
+		os.Exit(2)
+	}
+
+	catalog, err := ierror.LoadCatalog(os.DirFS("."), flag.Args()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	if err = catalog.WriteMarkdown(w); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"embed"
 	"errors"
 	"fmt"
 	"goplate"
@@ -11,37 +12,153 @@ import (
 	"goplate/http/server/interceptor"
 	"goplate/pkg/graceful"
 	"goplate/pkg/ierror"
+	"goplate/pkg/scheduler"
 	"goplate/pkg/trace_logger"
-	"sync"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	generic "github.com/rzaripov1990/genx"
 )
 
+//go:generate go run ./errdoc -o errors.md errors.yaml
+
+//go:embed errors.yaml
+var errorCatalog embed.FS
+
 func gracefulRun(_ context.Context, server *server.FiberServer) error {
 	return server.Run()
 }
 
+func gracefulDrain(_ context.Context, server *server.FiberServer) error {
+	server.Drain()
+	return nil
+}
+
 func gracefulStop(_ context.Context, server *server.FiberServer) error {
 	return server.Close()
 }
 
-func main() {
-	_, group := graceful.Prepare(context.Background())
+func closeReporter(ctx context.Context, reporter *ierror.FileReporter) error {
+	return reporter.Close(ctx)
+}
+
+func closeSentry(ctx context.Context, reporter *ierror.SentryReporter) error {
+	return reporter.Close(ctx)
+}
 
+func reopenReporter(ctx context.Context, reporter *ierror.FileReporter) error {
+	return reporter.Reopen(ctx)
+}
+
+// reporting sends errors to sentry when REPORT_SENTRY_DSN is set or to REPORT_FILE
+func reporting(cfg *env.BaseConfig, group *graceful.CloseGroup) (option ierror.Reporting) {
+	switch {
+	case cfg.Report.SentryDSN != "":
+		sentry, err := ierror.NewSentryReporter(ierror.SentryConfig{
+			DSN:         cfg.Report.SentryDSN,
+			Environment: cfg.Environment,
+			Release:     cfg.App.Version,
+		})
+		if err != nil {
+			panic(err)
+		}
+
+		option.Reporter = ierror.Dedup(sentry, cfg.Report.DedupWindow)
+		// queued reports are sent before exit
+		graceful.Close(group, sentry, closeSentry, graceful.PhaseFlush, graceful.Name{Value: "error reporter"})
+	case cfg.Report.File != "":
+		file, err := ierror.NewFileReporter(cfg.Report.File)
+		if err != nil {
+			panic(err)
+		}
+
+		option.Reporter = ierror.Dedup(file, cfg.Report.DedupWindow)
+		graceful.Close(group, file, closeReporter, graceful.PhaseFlush, graceful.Name{Value: "error reporter"})
+		// SIGHUP after rotation of the file
+		graceful.Reload(group, file, reopenReporter, graceful.Name{Value: "error reporter"})
+	}
+
+	return option
+}
+
+var errServerNotReady = errors.New("server is not ready")
+
+// background goroutines of fasthttp which outlive the server
+var fasthttpGoroutines = []string{
+	"github.com/valyala/fasthttp.updateServerDate",
+	"github.com/valyala/fasthttp.(*workerPool).Start",
+}
+
+func main() {
 	cfg := env.New()
-	log := trace_logger.New(cfg.Log.Level, true)
 
-	ierror.New(log)
+	// set by run, systemd watchdog keepalives are sent while the server is ready
+	var app atomic.Pointer[server.FiberServer]
+
+	options := []any{
+		graceful.ShutdownTimeout{Value: cfg.Shutdown.Timeout},
+		graceful.PreStop{Delay: cfg.Shutdown.PreStopDelay},
+		graceful.Upgrade{},
+		graceful.Health{Check: func(context.Context) error {
+			if fs := app.Load(); fs != nil && !fs.Ready() {
+				return errServerNotReady
+			}
+			return nil
+		}},
+	}
+	if cfg.Environment == "dev" {
+		// leaks of handlers are reported on shutdown
+		options = append(options, graceful.LeakCheck{Ignore: fasthttpGoroutines})
+	}
+
+	os.Exit(graceful.Run(
+		func(_ context.Context, group *graceful.CloseGroup) error {
+			return run(cfg, group, &app)
+		},
+		options...,
+	))
+}
+
+func run(cfg *env.BaseConfig, group *graceful.CloseGroup, health *atomic.Pointer[server.FiberServer]) error {
+	log := trace_logger.New(
+		cfg.Log.Level,
+		true,
+		trace_logger.NamedLevels{
+			Value: cfg.Log.Levels,
+		},
+		trace_logger.FlightRecorder{
+			Size:   cfg.Log.RecorderSize,
+			Traces: cfg.Log.RecorderTraces,
+		},
+	)
+
+	trace_logger.Bridge(log)
+
+	schema, ok := trace_logger.SchemaByName(cfg.Log.Schema)
+	if !ok {
+		return fmt.Errorf("unknown log schema: %s", cfg.Log.Schema)
+	}
+	errorFormat, err := reqresp.ParseErrorFormat(cfg.Http.ErrorFormat)
+	if err != nil {
+		return err
+	}
+	catalog, err := ierror.LoadCatalog(errorCatalog, "errors.yaml")
+	if err != nil {
+		return err
+	}
+	ierror.UseCatalog(catalog)
+	ierror.New(log, reporting(cfg, group))
 
 	mw := interceptor.Config{
-		Log:               log,
-		EnableLogRequest:  true,
-		EnableLogHeaders:  true,
-		EnableLogResponse: true,
-		EnableCatchPanic:  true,
-		MaskSensitiveData: true,
+		Log:                  log,
+		EnableLogRequest:     true,
+		EnableLogHeaders:     true,
+		EnableLogResponse:    true,
+		EnableCatchPanic:     true,
+		EnableFlightRecorder: true,
+		MaskSensitiveData:    true,
 		SensitiveData: interceptor.SensitiveData{
 			DeleteKeyInRequest:  []string{"file", "content"},
 			DeleteKeyInResponse: []string{"bodyB64", "file", "content"},
@@ -64,16 +181,38 @@ func main() {
 			},
 		},
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			c.Status(500).JSON(reqresp.NewError(500, err, "unhabdled error 3", generic.Ptr("E_MV")))
-			return nil
+			// status and message of unhandled errors are taken from the catalog
+			return reqresp.SendError(c, ierror.Wrap(err, "E_MV", 0, "").Render(c.Get(fiber.HeaderAcceptLanguage)))
 		},
 		SlowRequestDuration: 5 * time.Second,
+		Schema:              schema,
+		Problem: reqresp.ProblemConfig{
+			Format:   errorFormat,
+			TypeBase: cfg.Http.ProblemTypeBase,
+		},
+		Report: ierror.ReportIncident,
 	}
 
-	app := goplate.NewDefaultServer(cfg, log, mw)
+	// inherited from the previous binary on upgrade
+	listener, err := graceful.Listen(group, "tcp", ":"+cfg.Port)
+	if err != nil {
+		return err
+	}
+
+	// ready after Open tasks of the group
+	app := goplate.NewDefaultServer(cfg, log, mw).WithListener(listener).WithReadiness(group.IsReady)
+	health.Store(app)
+	app.App.Hooks().OnListen(func(fiber.ListenData) error {
+		group.Ready()
+		return nil
+	})
+	if cfg.Environment == "dev" {
+		app.WithDebugRouters()
+	}
 
 	app.App.Get("/", func(c *fiber.Ctx) error {
-		return reqresp.NewError(400, errors.New("bad request"), "see documentation", generic.Ptr("E_MODEL"))
+		// status and message are taken from the catalog
+		return ierror.Wrap(errors.New("bad request"), "E_MODEL", 0, "")
 	})
 
 	app.App.Get("/error", func(c *fiber.Ctx) error {
@@ -91,29 +230,98 @@ func main() {
 		)
 	})
 
+	app.App.Post("/post/xml", func(c *fiber.Ctx) error {
+		type request struct {
+			Phone string `json:"phone" xml:"phone" validate:"required"`
+		}
+
+		// the body is decoded by Content-Type, the response is encoded by Accept
+		req, err := reqresp.Bind[request](c)
+		if err != nil {
+			return err
+		}
+
+		return reqresp.Send(c, reqresp.NewData(req.Phone))
+	})
+
+	// validation errors are rendered with status 422 and fields
+	app.App.Post("/users/:id", reqresp.Handle(updateUser))
+
 	app.App.Get("/go", func(c *fiber.Ctx) error {
-		var wg sync.WaitGroup
+		// panics of goroutines are recovered and logged with trace_id of the request
+		fanout, _ := ierror.NewGroup(c.UserContext())
+		fanout.SetLimit(10)
 
 		count := 30
 		for i := 0; i < count-1; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				time.Sleep(time.Duration(i) * time.Second)
-			}()
+			fanout.Go(func(ctx context.Context) error {
+				select {
+				case <-time.After(time.Duration(i) * time.Second):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
 		}
-		wg.Wait()
+
+		if err := fanout.Wait(); err != nil {
+			return err
+		}
+
 		return c.JSON(reqresp.NewData("yeap"))
 	})
 
+	app.App.Get("/list", func(c *fiber.Ctx) error {
+		// errors of the query are rendered with status 400
+		query, err := reqresp.ParsePage(c, reqresp.PageConfig{Sort: []string{"id"}})
+		if err != nil {
+			return err
+		}
+
+		total := 95
+		items := make([]int, 0, query.Limit)
+		for id := query.Offset + 1; id <= total && len(items) < query.Limit; id++ {
+			items = append(items, id)
+		}
+
+		return c.JSON(reqresp.NewPage(c, query, items, int64(total)))
+	})
+
 	app.App.Get("/panic", func(c *fiber.Ctx) error {
 		panic("aaaaaa!!!")
 	})
 
 	log.Info("comp", "equal", generic.Equal("1", 1))
 
+	jobs := scheduler.New(log)
+	err = jobs.Add("heartbeat", func(ctx context.Context) error {
+		log.DebugContext(ctx, "heartbeat", "ready", group.IsReady())
+		return nil
+	}, scheduler.Cron{Expr: "*/5 * * * *"}, scheduler.Jitter{Value: 10 * time.Second})
+	if err != nil {
+		return err
+	}
+	jobs.Process(group)
+	app.WithJobsRouters(func() any {
+		return jobs.Jobs()
+	})
+
 	graceful.Process(group, app, gracefulRun)
-	graceful.Close(group, app, gracefulStop)
+	graceful.Drain(group, app, gracefulDrain, graceful.Name{Value: "http server"})
+	graceful.Close(group, app, gracefulStop, graceful.PhaseServer, graceful.Name{Value: "http server"})
+
+	return nil
+}
+
+type updateUserRequest struct {
+	ID       int    `params:"id" validate:"min=1"`
+	Name     string `json:"firstName" validate:"required,max=64"`
+	Phone    string `json:"phone" validate:"required,len=12"`
+	Password string `json:"password" validate:"omitempty,min=8"`
+	Source   string `reqHeader:"X-Source" validate:"omitempty,oneof=web mobile"`
+}
 
-	group.Wait(10 * time.Second)
+// updateUser doesn't depend on fiber, the request is bound and validated by reqresp.Handle
+func updateUser(_ context.Context, req updateUserRequest) (int, error) {
+	return req.ID, nil
 }
@@ -10,9 +10,9 @@ import (
 	"goplate/http/reqresp"
 	"goplate/http/server/interceptor"
 	"goplate/pkg/graceful"
+	"goplate/pkg/graceful/leaktest"
 	"goplate/pkg/ierror"
 	"goplate/pkg/trace_logger"
-	"sync"
 	"testing"
 	"time"
 
@@ -22,20 +22,44 @@ import (
 )
 
 func TestServer(t *testing.T) {
+	defer leaktest.Check(t, graceful.LeakCheck{Ignore: fasthttpGoroutines})()
+
 	_, group := graceful.Prepare(context.Background())
 
 	cfg := env.New()
-	log := trace_logger.New(cfg.Log.Level, true)
+	log := trace_logger.New(
+		cfg.Log.Level,
+		true,
+		trace_logger.NamedLevels{
+			Value: cfg.Log.Levels,
+		},
+		trace_logger.FlightRecorder{
+			Size:   cfg.Log.RecorderSize,
+			Traces: cfg.Log.RecorderTraces,
+		},
+	)
 
-	ierror.New(log)
+	trace_logger.Bridge(log)
+
+	schema, ok := trace_logger.SchemaByName(cfg.Log.Schema)
+	if !ok {
+		panic("unknown log schema: " + cfg.Log.Schema)
+	}
+	catalog, err := ierror.LoadCatalog(errorCatalog, "errors.yaml")
+	if err != nil {
+		panic(err)
+	}
+	ierror.UseCatalog(catalog)
+	ierror.New(log, reporting(cfg, group))
 
 	mw := interceptor.Config{
-		Log:               log,
-		EnableLogRequest:  true,
-		EnableLogHeaders:  true,
-		EnableLogResponse: true,
-		EnableCatchPanic:  true,
-		MaskSensitiveData: true,
+		Log:                  log,
+		EnableLogRequest:     true,
+		EnableLogHeaders:     true,
+		EnableLogResponse:    true,
+		EnableCatchPanic:     true,
+		EnableFlightRecorder: true,
+		MaskSensitiveData:    true,
 		SensitiveData: interceptor.SensitiveData{
 			DeleteKeyInRequest:  []string{"file", "content"},
 			DeleteKeyInResponse: []string{"bodyB64", "file", "content"},
@@ -58,16 +82,21 @@ func TestServer(t *testing.T) {
 			},
 		},
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			c.Status(500).JSON(reqresp.NewError(500, err, "unhabdled error 3", generic.Ptr("E_MV")))
-			return nil
+			return reqresp.SendError(c, ierror.Wrap(err, "E_MV", 0, "").Render(c.Get(fiber.HeaderAcceptLanguage)))
 		},
 		SlowRequestDuration: 5 * time.Second,
+		Schema:              schema,
+		Report:              ierror.ReportIncident,
 	}
 
 	app := goplate.NewDefaultServer(cfg, log, mw)
+	if cfg.Environment == "dev" {
+		app.WithDebugRouters()
+	}
 
 	app.App.Get("/", func(c *fiber.Ctx) error {
-		return reqresp.NewError(400, errors.New("bad request"), "see documentation", generic.Ptr("E_MODEL"))
+		// status and message are taken from the catalog
+		return ierror.Wrap(errors.New("bad request"), "E_MODEL", 0, "")
 	})
 
 	app.App.Get("/error", func(c *fiber.Ctx) error {
@@ -86,17 +115,26 @@ func TestServer(t *testing.T) {
 	})
 
 	app.App.Get("/go", func(c *fiber.Ctx) error {
-		var wg sync.WaitGroup
+		// panics of goroutines are recovered and logged with trace_id of the request
+		fanout, _ := ierror.NewGroup(c.UserContext())
+		fanout.SetLimit(10)
 
 		count := 30
 		for i := 0; i < count-1; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				time.Sleep(time.Duration(i) * time.Second)
-			}()
+			fanout.Go(func(ctx context.Context) error {
+				select {
+				case <-time.After(time.Duration(i) * time.Second):
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
 		}
-		wg.Wait()
+
+		if err := fanout.Wait(); err != nil {
+			return err
+		}
+
 		return c.JSON(reqresp.NewData("yeap"))
 	})
 
@@ -107,7 +145,8 @@ func TestServer(t *testing.T) {
 	log.Info("comp", "equal", generic.Equal("1", 1))
 
 	graceful.Process(group, app, gracefulRun)
-	graceful.Close(group, app, gracefulStop)
+	graceful.Drain(group, app, gracefulDrain, graceful.Name{Value: "http server"})
+	graceful.Close(group, app, gracefulStop, graceful.PhaseServer, graceful.Name{Value: "http server"})
 
 	group.Wait(10 * time.Second)
 }
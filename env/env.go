@@ -10,6 +10,8 @@ type (
 		Environment string      `name:"ENVIRONMENT" default:"dev" required:"true"`
 		App         Application `name:"APP"`
 		Log         Log         `name:"LOG"`
+		Report      Report      `name:"REPORT"`
+		Shutdown    Shutdown    `name:"SHUTDOWN"`
 		Http        Http
 	}
 
@@ -19,7 +21,22 @@ type (
 	}
 
 	Log struct {
-		Level string `name:"LEVEL" default:"DEBUG"`
+		Level          string `name:"LEVEL" default:"DEBUG"`
+		Levels         string `name:"LEVELS"`
+		Schema         string `name:"SCHEMA" default:"default"`
+		RecorderSize   int    `name:"RECORDER_SIZE" default:"64"`
+		RecorderTraces int    `name:"RECORDER_TRACES" default:"1024"`
+	}
+
+	Report struct {
+		SentryDSN   string        `name:"SENTRY_DSN"`
+		File        string        `name:"FILE"`
+		DedupWindow time.Duration `name:"DEDUP_WINDOW" default:"1m"`
+	}
+
+	Shutdown struct {
+		Timeout      time.Duration `name:"TIMEOUT" default:"10s"`
+		PreStopDelay time.Duration `name:"PRE_STOP_DELAY" default:"0s"`
 	}
 
 	Http struct {
@@ -31,5 +48,8 @@ type (
 		WriteTimeout        time.Duration `name:"WRITE_TIMEOUT" default:"60s"`
 		MaxIdleConns        int           `name:"HTTP_MAX_IDLE_CONNS" default:"10"`
 		MaxIdleConnsPerHost int           `name:"HTTP_MAX_IDLE_CONNS_PER_HOST" default:"10"`
+		// "envelope", "problem" or empty to negotiate by Accept header
+		ErrorFormat     string `name:"HTTP_ERROR_FORMAT"`
+		ProblemTypeBase string `name:"HTTP_PROBLEM_TYPE_BASE"`
 	}
 )
@@ -18,6 +18,7 @@ type (
 		retryCount int
 		client     *fasthttp.Client
 		baseUrl    string
+		schema     trace_logger.Schema
 	}
 
 	Config struct {
@@ -27,12 +28,15 @@ type (
 		MaxIdleConns        int
 		MaxIdleConnsPerHost int
 		RetryIfCount        int
+		// Attribute keys of request logs, default trace_logger.SchemaDefault
+		Schema trace_logger.Schema
 	}
 
 	Request struct {
 		client  *fasthttp.Client
 		headers map[string]string
 		uri     string
+		schema  trace_logger.Schema
 	}
 )
 
@@ -41,6 +45,11 @@ func New(cfg Config) *Http {
 		withRetry:  cfg.RetryIfCount >= 1,
 		baseUrl:    cfg.BaseUrl,
 		retryCount: cfg.RetryIfCount,
+		schema:     cfg.Schema,
+	}
+
+	if fhc.schema.Name == "" {
+		fhc.schema = trace_logger.SchemaDefault
 	}
 
 	fhc.client = &fasthttp.Client{
@@ -65,6 +74,7 @@ func (fhc *Http) NewRequest(path string, headers map[string]string) *Request {
 		headers: headers,
 		uri:     fhc.baseUrl + path,
 		client:  fhc.client,
+		schema:  fhc.schema,
 	}
 	return value
 }
@@ -91,47 +101,43 @@ func Get[T_response any](ctx context.Context, log *slog.Logger, req *Request) (r
 	var slogValues []slog.Attr
 	slogValues, err = req.do(fastreq, fastresp, &resp)
 
-	trace_logger.L(ctx, log).LogAttrs(ctx, slog.LevelDebug, "logging request", slogValues...)
+	req.schema.L(ctx, trace_logger.Component(log, "http.client")).LogAttrs(ctx, slog.LevelDebug, "logging request", slogValues...)
 	return
 }
 
 func (fr *Request) do(fastreq *fasthttp.Request, fastresp *fasthttp.Response, resp any) (slogValues []slog.Attr, err error) {
 	var (
 		start = time.Now()
+		keys  = fr.schema.Client
 	)
 
-	slogValues = append(slogValues,
-		slog.String("request.url", string(fastreq.RequestURI())),
-		slog.String("request.method", string(fastreq.Header.Method())),
-	)
+	slogValues = trace_logger.Append(slogValues, keys.URL, string(fastreq.RequestURI()))
+	slogValues = trace_logger.Append(slogValues, keys.Method, string(fastreq.Header.Method()))
+
+	headers := make(map[string]string)
 	for _, value := range fastreq.Header.PeekKeys() {
-		slogValues = append(slogValues, slog.String("request.header."+string(value), string(fastreq.Header.Peek(string(value)))))
+		headers[string(value)] = string(fastreq.Header.Peek(string(value)))
 	}
+	slogValues = keys.AppendHeaders(slogValues, headers)
 
 	err = fr.client.Do(fastreq, fastresp)
 	duration := time.Since(start)
 	if err != nil {
-		slogValues = append(slogValues,
-			slog.String("response.error", err.Error()),
-			slog.Duration("response.duration_nanosec", duration),
-			slog.String("response.duration", duration.String()),
-		)
+		slogValues = trace_logger.Append(slogValues, keys.Error, err.Error())
+		slogValues = keys.AppendDuration(slogValues, duration)
 		return
 	}
 
-	slogValues = append(slogValues,
-		slog.Duration("response.duration_nanosec", duration),
-		slog.String("response.duration", duration.String()),
-		slog.String("response.content_type", string(fastresp.Header.Peek(fasthttp.HeaderContentType))),
-		slog.Int("response.code", fastresp.StatusCode()),
-	)
+	slogValues = keys.AppendDuration(slogValues, duration)
+	slogValues = trace_logger.Append(slogValues, keys.ContentType, string(fastresp.Header.Peek(fasthttp.HeaderContentType)))
+	slogValues = trace_logger.Append(slogValues, keys.StatusCode, fastresp.StatusCode())
 
 	body := fastresp.Body()
 	if len(body) > 0 {
 		if err = json.Unmarshal(body, resp); err == nil {
-			slogValues = append(slogValues, slog.Any("response.body", resp))
+			slogValues = trace_logger.Append(slogValues, keys.ResponseBody, resp)
 		} else {
-			slogValues = append(slogValues, slog.String("response.error", err.Error()))
+			slogValues = trace_logger.Append(slogValues, keys.Error, err.Error())
 			return
 		}
 	}
@@ -0,0 +1,427 @@
+package reqresp
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const localsPayload = "reqresp.payload"
+
+var (
+	msgTypeBind       = "E_BIND"
+	msgTypeValidation = "E_VALIDATION"
+)
+
+// Bind decodes the body (registered codecs, form, multipart) into T, then `query`, `params` and `reqHeader` tagged fields
+// from the query, path params and headers, other fields aren't taken from them. The value is validated by `validate` tags:
+// required, omitempty, min=, max=, len=, oneof=a b, email, url. Nested structs and slices are validated too.
+// The error is *Error, 400 for decoding and 422 with Fields for validation, 500 for invalid tags of T.
+// The bound value is logged masked by the interceptor
+func Bind[T any](c *fiber.Ctx) (value T, err error) {
+	if len(c.Body()) > 0 {
+		if codec, ok := CodecFor(string(c.Request().Header.ContentType())); ok {
+			err = codec.Unmarshal(c.Body(), &value)
+		} else {
+			err = c.BodyParser(&value)
+		}
+		if err != nil {
+			return value, NewError(fiber.StatusBadRequest, err, "invalid request body", &msgTypeBind)
+		}
+	}
+
+	if len(c.Request().URI().QueryString()) > 0 {
+		if err = bindTagged(&value, "query", c.QueryParser); err != nil {
+			return value, NewError(fiber.StatusBadRequest, err, "invalid query", &msgTypeBind)
+		}
+	}
+
+	if len(c.Route().Params) > 0 {
+		if err = bindTagged(&value, "params", c.ParamsParser); err != nil {
+			return value, NewError(fiber.StatusBadRequest, err, "invalid path params", &msgTypeBind)
+		}
+	}
+
+	if err = bindTagged(&value, "reqHeader", c.ReqHeaderParser); err != nil {
+		return value, NewError(fiber.StatusBadRequest, err, "invalid headers", &msgTypeBind)
+	}
+
+	c.Locals(localsPayload, value)
+
+	fields, err := Validate(value)
+	if err != nil {
+		return value, NewError(fiber.StatusInternalServerError, err, "invalid validation rules", nil)
+	}
+	if fields != nil {
+		e := NewError(fiber.StatusUnprocessableEntity, nil, "validation failed", &msgTypeValidation)
+		e.Fields = fields
+		return value, e
+	}
+
+	return value, nil
+}
+
+// Payload returns the value bound by Bind for the request
+func Payload(c *fiber.Ctx) any {
+	return c.Locals(localsPayload)
+}
+
+// bindTagged parses into a new T and copies only set fields with the tag,
+// fiber parsers fill untagged fields by the field name
+func bindTagged[T any](value *T, tag string, parse func(out any) error) error {
+	if !hasTag(reflect.TypeOf(value), tag) {
+		return nil
+	}
+
+	var parsed T
+	if err := parse(&parsed); err != nil {
+		return err
+	}
+
+	copyTagged(reflect.ValueOf(value).Elem(), reflect.ValueOf(parsed), tag)
+	return nil
+}
+
+func copyTagged(dst, src reflect.Value, tag string) {
+	if dst.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < dst.NumField(); i++ {
+		sf := dst.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		if _, ok := sf.Tag.Lookup(tag); ok {
+			if !src.Field(i).IsZero() {
+				dst.Field(i).Set(src.Field(i))
+			}
+		} else if sf.Anonymous {
+			copyTagged(dst.Field(i), src.Field(i), tag)
+		}
+	}
+}
+
+// hasTag reports whether a field of the struct has the tag
+func hasTag(t reflect.Type, tag string) bool {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if _, ok := sf.Tag.Lookup(tag); ok || (sf.Anonymous && hasTag(sf.Type, tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks `validate` tags of the struct, see Bind.
+// Tags are parsed once per type, the error of invalid tags is returned for every value of the type
+func Validate(value any) (fields []FieldError, err error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(value)
+	if err = rulesError(v.Type()); err != nil {
+		return nil, err
+	}
+
+	validateValue(v, "", &fields)
+	return fields, nil
+}
+
+func validateValue(v reflect.Value, path string, fields *[]FieldError) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range rulesOf(v.Type()).fields {
+			fieldPath := joinPath(path, f.name)
+			if f.embedded {
+				// embedded fields are on the same level
+				fieldPath = path
+			}
+
+			if failed := validateField(v.Field(f.index), f.rules, fieldPath, fields); !failed {
+				validateValue(v.Field(f.index), fieldPath, fields)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", fields)
+		}
+	}
+}
+
+// validateField applies the rules, it stops on the first failed rule
+func validateField(v reflect.Value, rules []rule, path string, fields *[]FieldError) (failed bool) {
+	for _, r := range rules {
+		if r.name == "omitempty" {
+			if v.IsZero() {
+				return false
+			}
+			continue
+		}
+
+		if msg := check(v, r); msg != "" {
+			*fields = append(*fields, FieldError{Path: path, Rule: r.tag, Message: msg})
+			return true
+		}
+	}
+
+	return false
+}
+
+// check returns the message when the rule fails
+func check(v reflect.Value, r rule) string {
+	if r.name == "required" {
+		if v.IsZero() || (isList(v) && v.Len() == 0) {
+			return "is required"
+		}
+		return ""
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			// nil is checked by required only
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max", "len":
+		size, unit := measure(v)
+		switch {
+		case r.name == "min" && size < r.limit:
+			return fmt.Sprintf("must be at least %s%s", r.param, unit)
+		case r.name == "max" && size > r.limit:
+			return fmt.Sprintf("must be at most %s%s", r.param, unit)
+		case r.name == "len" && size != r.limit:
+			return fmt.Sprintf("must be exactly %s%s", r.param, unit)
+		}
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, allowed := range strings.Fields(r.param) {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(strings.Fields(r.param), ", ")
+	case "email":
+		address, err := mail.ParseAddress(v.String())
+		if err != nil || address.Address != v.String() {
+			return "must be an email"
+		}
+	case "url":
+		u, err := url.ParseRequestURI(v.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an url"
+		}
+	}
+
+	return ""
+}
+
+// measure is the length of strings and lists or the value of numbers, kinds are checked by compileRules
+func measure(v reflect.Value) (size float64, unit string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	return 0, ""
+}
+
+func isList(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// fieldName is the name of the field in the request: json, form, query, params or reqHeader tag, otherwise the field name
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"json", "form", "query", "params", "reqHeader"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+type (
+	// typeRules are parsed `validate` tags of the struct type
+	typeRules struct {
+		fields []fieldRules
+		err    error
+	}
+
+	fieldRules struct {
+		index    int
+		name     string
+		embedded bool
+		rules    []rule
+	}
+
+	rule struct {
+		// the item of the tag, e.g. "min=1"
+		tag, name, param string
+		limit            float64
+	}
+
+	rulesResult struct {
+		err error
+	}
+)
+
+var (
+	// reflect.Type of structs to *typeRules
+	rulesCache sync.Map
+	// reflect.Type of validated values to rulesResult, errors of nested types included
+	rulesErrors sync.Map
+)
+
+func rulesOf(t reflect.Type) *typeRules {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.(*typeRules)
+	}
+
+	tr := &typeRules{}
+	tr.fields, tr.err = compileRules(t)
+
+	cached, _ := rulesCache.LoadOrStore(t, tr)
+	return cached.(*typeRules)
+}
+
+// rulesError checks tags of the type and its nested types once
+func rulesError(t reflect.Type) error {
+	if cached, ok := rulesErrors.Load(t); ok {
+		return cached.(rulesResult).err
+	}
+
+	err := walkRules(t, make(map[reflect.Type]bool))
+	rulesErrors.Store(t, rulesResult{err: err})
+	return err
+}
+
+func walkRules(t reflect.Type, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	tr := rulesOf(t)
+	if tr.err != nil {
+		return tr.err
+	}
+
+	for _, f := range tr.fields {
+		if err := walkRules(t.Field(f.index).Type, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compileRules(t reflect.Type) ([]fieldRules, error) {
+	var fields []fieldRules
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := fieldRules{index: i, name: fieldName(sf), embedded: sf.Anonymous}
+
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			for _, item := range strings.Split(tag, ",") {
+				r, err := compileRule(sf.Type, item)
+				if err != nil {
+					return nil, fmt.Errorf("reqresp: %s.%s: %w", t, sf.Name, err)
+				}
+				f.rules = append(f.rules, r)
+			}
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+func compileRule(t reflect.Type, item string) (r rule, err error) {
+	r.tag = item
+	r.name, r.param, _ = strings.Cut(item, "=")
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch r.name {
+	case "required", "omitempty":
+	case "min", "max", "len":
+		if r.limit, err = strconv.ParseFloat(r.param, 64); err != nil {
+			return r, fmt.Errorf("invalid %s", item)
+		}
+
+		switch t.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return r, fmt.Errorf("%s isn't supported by %s", r.name, t.Kind())
+		}
+	case "oneof":
+		if len(strings.Fields(r.param)) == 0 {
+			return r, fmt.Errorf("invalid %s", item)
+		}
+	case "email", "url":
+		if t.Kind() != reflect.String {
+			return r, fmt.Errorf("%s isn't supported by %s", r.name, t.Kind())
+		}
+	default:
+		return r, fmt.Errorf("unknown validate rule %s", r.name)
+	}
+
+	return r, nil
+}
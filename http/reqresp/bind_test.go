@@ -0,0 +1,130 @@
+package reqresp
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type (
+	address struct {
+		City string `json:"city" validate:"required"`
+	}
+
+	createUser struct {
+		Tenant   string    `params:"tenant" validate:"len=4"`
+		DryRun   bool      `query:"dryRun"`
+		Name     string    `json:"name" validate:"required,min=2,max=10"`
+		Email    string    `json:"email" validate:"omitempty,email"`
+		Age      *int      `json:"age" validate:"omitempty,min=18"`
+		Role     string    `json:"role" validate:"oneof=admin user"`
+		Tags     []string  `json:"tags" validate:"max=2"`
+		Password string    `json:"password" validate:"required"`
+		Address  []address `json:"addresses" validate:"required"`
+	}
+)
+
+func TestBind(t *testing.T) {
+	var (
+		bound  createUser
+		fields []FieldError
+	)
+
+	app := fiber.New()
+	app.Post("/:tenant/users", func(c *fiber.Ctx) error {
+		var err error
+		bound, err = Bind[createUser](c)
+		if err != nil {
+			e := err.(*Error)
+			fields = e.Fields
+			return c.SendStatus(e.StatusCode)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	send := func(target, body string) int {
+		req := httptest.NewRequest(fiber.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	status := send("/acme/users?dryRun=true",
+		`{"name":"Bob","role":"user","password":"x","addresses":[{"city":"Almaty"}]}`)
+	if status != fiber.StatusOK || bound.Tenant != "acme" || !bound.DryRun || bound.Address[0].City != "Almaty" {
+		t.Fatalf("unexpected bind: %d %+v", status, bound)
+	}
+
+	status = send("/abc/users",
+		`{"name":"B","email":"bob","age":16,"role":"root","tags":["a","b","c"],"addresses":[{"city":"Almaty"},{}]}`)
+
+	expected := map[string]string{
+		"tenant":            "len=4",
+		"name":              "min=2",
+		"email":             "email",
+		"age":               "min=18",
+		"role":              "oneof=admin user",
+		"tags":              "max=2",
+		"password":          "required",
+		"addresses[1].city": "required",
+	}
+	if status != fiber.StatusUnprocessableEntity || len(fields) != len(expected) {
+		t.Fatalf("unexpected validation: %d %+v", status, fields)
+	}
+	for _, field := range fields {
+		if expected[field.Path] != field.Rule || field.Message == "" {
+			t.Errorf("unexpected field error: %+v", field)
+		}
+	}
+
+	if status := send("/acme/users", `{"name":`); status != fiber.StatusBadRequest {
+		t.Errorf("expected 400 for invalid json, got %d", status)
+	}
+
+	// only tagged fields are taken from the query and path
+	status = send("/acme/users?dryRun=true&Name=evil&password=short&tenant=evil",
+		`{"name":"Bob","role":"user","password":"x","addresses":[{"city":"Almaty"}]}`)
+	if status != fiber.StatusOK || bound.Name != "Bob" || bound.Password != "x" || bound.Tenant != "acme" || !bound.DryRun {
+		t.Errorf("body fields must not be overwritten: %d %+v", status, bound)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	type (
+		nested struct {
+			Flag bool `validate:"min=1"`
+		}
+		invalid struct {
+			Items []nested
+		}
+	)
+
+	for _, value := range []any{
+		struct {
+			Name string `validate:"min=abc"`
+		}{},
+		struct {
+			Name string `validate:"unknown"`
+		}{},
+		struct {
+			Age int `validate:"email"`
+		}{},
+		invalid{},
+	} {
+		// the error is cached by type
+		for i := 0; i < 2; i++ {
+			if _, err := Validate(value); err == nil {
+				t.Errorf("%T: expected error of rules", value)
+			}
+		}
+	}
+
+	if fields, err := Validate(createUser{Tenant: "acme", Name: "Bob", Role: "user", Password: "x", Address: []address{{City: "A"}}}); err != nil || fields != nil {
+		t.Errorf("unexpected validation %v %v", fields, err)
+	}
+}
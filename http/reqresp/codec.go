@@ -0,0 +1,245 @@
+package reqresp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type (
+	// Codec encodes responses and decodes requests of the content type, field names are taken from json tags (xml tags for XML).
+	// Unmarshal into *map[string]any is used by the interceptor to log and mask bodies
+	Codec interface {
+		ContentType() string
+		Marshal(value any) ([]byte, error)
+		Unmarshal(data []byte, value any) error
+	}
+
+	jsonCodec    struct{}
+	xmlCodec     struct{}
+	msgpackCodec struct{}
+	cborCodec    struct{}
+)
+
+const (
+	MIMEApplicationMsgPack = "application/msgpack"
+	MIMEApplicationCBOR    = "application/cbor"
+)
+
+var (
+	codecsMu sync.RWMutex
+	// the first codec is the default of Send
+	codecs = []Codec{jsonCodec{}, xmlCodec{}, msgpackCodec{}, cborCodec{}}
+
+	cborDecoder, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+)
+
+// RegisterCodec adds the codec or replaces the codec of the same content type
+func RegisterCodec(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	for i := range codecs {
+		if codecs[i].ContentType() == codec.ContentType() {
+			codecs[i] = codec
+			return
+		}
+	}
+	codecs = append(codecs, codec)
+}
+
+// CodecFor returns the codec of Content-Type value, parameters and +json/+xml suffixes are respected
+func CodecFor(contentType string) (Codec, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, codec := range codecs {
+		if mediaType == codec.ContentType() {
+			return codec, true
+		}
+	}
+
+	// e.g. application/problem+json, text/xml
+	for _, codec := range codecs {
+		_, subtype, _ := strings.Cut(codec.ContentType(), "/")
+		if strings.HasSuffix(mediaType, "+"+subtype) || mediaType == "text/"+subtype {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+// negotiate returns the codec accepted by the request, the default codec otherwise
+func negotiate(c *fiber.Ctx) Codec {
+	codecsMu.RLock()
+	offers := make([]string, len(codecs))
+	for i, codec := range codecs {
+		offers[i] = codec.ContentType()
+	}
+	codecsMu.RUnlock()
+
+	if accepted := c.Accepts(offers...); accepted != "" {
+		if codec, ok := CodecFor(accepted); ok {
+			return codec
+		}
+	}
+	return jsonCodec{}
+}
+
+// Send writes the value in the codec accepted by the request, JSON by default
+func Send(c *fiber.Ctx, value any) error {
+	codec := negotiate(c)
+
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, codec.ContentType())
+	return c.Send(data)
+}
+
+func (jsonCodec) ContentType() string {
+	return fiber.MIMEApplicationJSON
+}
+
+func (jsonCodec) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (jsonCodec) Unmarshal(data []byte, value any) error {
+	return json.Unmarshal(data, value)
+}
+
+func (xmlCodec) ContentType() string {
+	return fiber.MIMEApplicationXML
+}
+
+// Marshal writes the value as <response>, names of generic types aren't valid elements. Maps aren't supported by encoding/xml
+func (xmlCodec) Marshal(value any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := xml.NewEncoder(&buf)
+	if err := encoder.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: "response"}}); err != nil {
+		return nil, err
+	}
+
+	err := encoder.Flush()
+	return buf.Bytes(), err
+}
+
+func (xmlCodec) Unmarshal(data []byte, value any) error {
+	if m, ok := value.(*map[string]any); ok {
+		return decodeXMLMap(data, m)
+	}
+	return xml.Unmarshal(data, value)
+}
+
+// decodeXMLMap reads children of the root element into the map: leaves are strings, repeated elements are slices
+func decodeXMLMap(data []byte, m *map[string]any) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		stack = []map[string]any{{}}
+		names []string
+		text  strings.Builder
+	)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			stack = append(stack, map[string]any{})
+			names = append(names, t.Name.Local)
+			text.Reset()
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			name := names[len(names)-1]
+			names = names[:len(names)-1]
+
+			var value any = node
+			if len(node) == 0 {
+				value = strings.TrimSpace(text.String())
+			}
+			text.Reset()
+
+			parent := stack[len(stack)-1]
+			switch existing := parent[name].(type) {
+			case nil:
+				parent[name] = value
+			case []any:
+				parent[name] = append(existing, value)
+			default:
+				parent[name] = []any{existing, value}
+			}
+		}
+	}
+
+	// skip the root element
+	for _, root := range stack[0] {
+		if typed, ok := root.(map[string]any); ok {
+			*m = typed
+			return nil
+		}
+	}
+	*m = map[string]any{}
+	return nil
+}
+
+func (msgpackCodec) ContentType() string {
+	return MIMEApplicationMsgPack
+}
+
+func (msgpackCodec) Marshal(value any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoder := msgpack.NewEncoder(&buf)
+	// omitempty of json tags is respected, so the shape is the same as of JSON
+	encoder.SetCustomStructTag("json")
+
+	err := encoder.Encode(value)
+	return buf.Bytes(), err
+}
+
+func (msgpackCodec) Unmarshal(data []byte, value any) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+
+	return decoder.Decode(value)
+}
+
+func (cborCodec) ContentType() string {
+	return MIMEApplicationCBOR
+}
+
+func (cborCodec) Marshal(value any) ([]byte, error) {
+	return cbor.Marshal(value)
+}
+
+func (cborCodec) Unmarshal(data []byte, value any) error {
+	return cborDecoder.Unmarshal(data, value)
+}
@@ -0,0 +1,143 @@
+package reqresp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCodecs(t *testing.T) {
+	type request struct {
+		Phone string `json:"phone" xml:"phone" validate:"required"`
+	}
+
+	app := fiber.New()
+	app.Post("/post", func(c *fiber.Ctx) error {
+		req, err := Bind[request](c)
+		if err != nil {
+			return SendError(c, err.(*Error))
+		}
+		return Send(c, NewData(req.Phone))
+	})
+
+	for _, ctype := range []string{fiber.MIMEApplicationJSON, fiber.MIMEApplicationXML, MIMEApplicationMsgPack, MIMEApplicationCBOR} {
+		codec, ok := CodecFor(ctype + "; charset=utf-8")
+		if !ok || codec.ContentType() != ctype {
+			t.Fatalf("no codec for %s", ctype)
+		}
+
+		body, err := codec.Marshal(request{Phone: "+70000000000"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(fiber.MethodPost, "/post", bytes.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, ctype)
+		req.Header.Set(fiber.HeaderAccept, ctype)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != ctype {
+			t.Fatalf("%s: unexpected response %d %s", ctype, resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+		}
+
+		data, _ := io.ReadAll(resp.Body)
+		var result map[string]any
+		if err := codec.Unmarshal(data, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result["data"] != "+70000000000" || (result["success"] != true && result["success"] != "true") {
+			t.Errorf("%s: unexpected body %v", ctype, result)
+		}
+	}
+
+	// envelopes have the same shape in every codec: zero values without omitempty and empty lists are kept
+	msgType := "E_BAD"
+	for _, value := range []any{
+		NewError(fiber.StatusBadRequest, nil, "bad", &msgType),
+		Page[int]{Data: []int{}},
+	} {
+		var expected map[string]any
+		data, _ := json.Marshal(value)
+		_ = json.Unmarshal(data, &expected)
+
+		for _, codec := range []Codec{msgpackCodec{}, cborCodec{}} {
+			data, err := codec.Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var result map[string]any
+			if err := codec.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(normalize(result)) != fmt.Sprint(expected) {
+				t.Errorf("%s: expected %v, got %v", codec.ContentType(), expected, result)
+			}
+		}
+	}
+
+	e := NewError(fiber.StatusBadRequest, nil, "bad", &msgType)
+	data, err := xmlCodec{}.Marshal(e)
+	if err != nil || string(data) != "<response><success>false</success><msg>bad</msg><msgType>E_BAD</msgType></response>" {
+		t.Errorf("unexpected xml envelope %s %v", data, err)
+	}
+
+	// the error envelope is encoded by Accept, JSON by default
+	req := httptest.NewRequest(fiber.MethodPost, "/post", bytes.NewReader([]byte("<request></request>")))
+	req.Header.Set(fiber.HeaderContentType, "text/xml")
+	req.Header.Set(fiber.HeaderAccept, fiber.MIMEApplicationXML)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusUnprocessableEntity ||
+		!bytes.Contains(data, []byte("<msgType>E_VALIDATION</msgType>")) || !bytes.Contains(data, []byte("<path>phone</path>")) {
+		t.Errorf("unexpected xml error %d %s", resp.StatusCode, data)
+	}
+
+	if codec := negotiate(app.AcquireCtx(&fasthttp.RequestCtx{})); codec.ContentType() != fiber.MIMEApplicationJSON {
+		t.Errorf("unexpected default codec %s", codec.ContentType())
+	}
+}
+
+func TestDecodeXMLMap(t *testing.T) {
+	var source map[string]any
+	err := xmlCodec{}.Unmarshal([]byte(`<request><phone> +7 </phone><tag>a</tag><tag>b</tag><user><name>n</name></user></request>`), &source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, _ := source["tag"].([]any)
+	user, _ := source["user"].(map[string]any)
+	if source["phone"] != "+7" || len(tags) != 2 || user["name"] != "n" {
+		t.Errorf("unexpected map %v", source)
+	}
+}
+
+// normalize converts numbers and lists of codecs to the types of encoding/json
+func normalize(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			typed[key] = normalize(item)
+		}
+	case []any:
+		for i, item := range typed {
+			typed[i] = normalize(item)
+		}
+	case int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		var f float64
+		_, _ = fmt.Sscan(fmt.Sprint(typed), &f)
+		return f
+	}
+	return value
+}
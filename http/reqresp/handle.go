@@ -0,0 +1,63 @@
+package reqresp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StatusClientClosedRequest is the status of requests cancelled by the client (nginx)
+const StatusClientClosedRequest = 499
+
+var (
+	msgTypeTimeout   = "E_TIMEOUT"
+	msgTypeCancelled = "E_CANCELLED"
+)
+
+// Handle adapts the business handler to fiber: Req is bound and validated by Bind (body, query, path params and headers),
+// fn gets the user context with trace_id, Resp is sent as Data in the codec accepted by the request.
+// *Error, *fiber.Error and context errors are rendered with their status by SendError,
+// other errors are returned to the interceptor or ErrorHandler, e.g. errors with the catalog are rendered by Accept-Language
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req, err := Bind[Req](c)
+		if err != nil {
+			return SendError(c, err.(*Error))
+		}
+
+		resp, err := fn(c.UserContext(), req)
+		if err != nil {
+			// renderers keep their own status even when the cause is a context error
+			var renderer Renderer
+			if errors.As(err, &renderer) {
+				if e, ok := renderer.(*Error); ok {
+					return SendError(c, e)
+				}
+				return err
+			}
+
+			if e := handlerError(err); e != nil {
+				return SendError(c, e)
+			}
+			return err
+		}
+
+		return Send(c, NewData(resp))
+	}
+}
+
+// handlerError maps err to Error with the status, nil when the status isn't known
+func handlerError(err error) *Error {
+	var fiberError *fiber.Error
+
+	switch {
+	case errors.As(err, &fiberError):
+		return NewError(fiberError.Code, err, fiberError.Message, nil)
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewError(fiber.StatusGatewayTimeout, err, "request timeout", &msgTypeTimeout)
+	case errors.Is(err, context.Canceled):
+		return NewError(StatusClientClosedRequest, err, "request cancelled", &msgTypeCancelled)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package reqresp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type (
+	getOrder struct {
+		ID     int    `params:"id" validate:"min=1"`
+		Tenant string `reqHeader:"X-Tenant" validate:"required"`
+		Expand bool   `query:"expand"`
+	}
+
+	order struct {
+		ID      int    `json:"id"`
+		Tenant  string `json:"tenant"`
+		Expand  bool   `json:"expand"`
+		TraceID string `json:"traceId"`
+	}
+)
+
+var errNotFound = "E_NOT_FOUND"
+
+// upstreamError renders its own response like errors with the catalog
+type upstreamError struct {
+	cause error
+}
+
+func (e upstreamError) Error() string {
+	return "upstream unavailable: " + e.cause.Error()
+}
+
+func (e upstreamError) Unwrap() error {
+	return e.cause
+}
+
+func (e upstreamError) Render(_ string) *Error {
+	msgType := "E_UPSTREAM"
+	return NewError(fiber.StatusServiceUnavailable, e, "upstream unavailable", &msgType)
+}
+
+// the handler is tested without fiber
+func findOrder(ctx context.Context, req getOrder) (order, error) {
+	switch req.ID {
+	case 404:
+		return order{}, NewError(fiber.StatusNotFound, nil, "order not found", &errNotFound)
+	case 410:
+		return order{}, fiber.ErrGone
+	case 504:
+		return order{}, fmt.Errorf("query: %w", context.DeadlineExceeded)
+	case 503:
+		return order{}, upstreamError{cause: context.DeadlineExceeded}
+	case 500:
+		return order{}, errors.New("unknown")
+	}
+
+	traceID, _ := ctx.Value(trace_context.TraceKeyInCtx).(string)
+	return order{ID: req.ID, Tenant: req.Tenant, Expand: req.Expand, TraceID: traceID}, nil
+}
+
+func TestHandle(t *testing.T) {
+	if _, err := findOrder(context.Background(), getOrder{ID: 404}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// errors are rendered without ErrorHandler, renderers are left to the interceptor
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(trace_context.SetTraceID(context.Background(), "abc"))
+
+		err := c.Next()
+		var renderer Renderer
+		if errors.As(err, &renderer) {
+			return SendError(c, renderer.Render(c.Get(fiber.HeaderAcceptLanguage)))
+		}
+		return err
+	})
+	app.Get("/orders/:id", Handle(findOrder))
+
+	send := func(target, tenant string) (int, map[string]any) {
+		req := httptest.NewRequest(fiber.MethodGet, target, nil)
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+
+		var result map[string]any
+		_ = json.Unmarshal(body, &result)
+		return resp.StatusCode, result
+	}
+
+	status, body := send("/orders/7?expand=true", "acme")
+	data, _ := body["data"].(map[string]any)
+	if status != fiber.StatusOK || body["success"] != true ||
+		data["id"] != 7.0 || data["tenant"] != "acme" || data["expand"] != true || data["traceId"] != "abc" {
+		t.Errorf("unexpected response %d %v", status, body)
+	}
+
+	status, body = send("/orders/0", "")
+	if fields, _ := json.Marshal(body["fields"]); status != fiber.StatusUnprocessableEntity ||
+		!strings.Contains(string(fields), `"path":"id"`) || !strings.Contains(string(fields), `"path":"X-Tenant"`) {
+		t.Errorf("unexpected validation %d %v", status, body)
+	}
+
+	for target, expected := range map[string]int{
+		"/orders/404": fiber.StatusNotFound,
+		"/orders/410": fiber.StatusGone,
+		"/orders/504": fiber.StatusGatewayTimeout,
+		"/orders/503": fiber.StatusServiceUnavailable,
+	} {
+		status, body = send(target, "acme")
+		if status != expected || body["success"] != false || body["msg"] == nil {
+			t.Errorf("%s: unexpected error %d %v", target, status, body)
+		}
+	}
+
+	if _, body = send("/orders/503", "acme"); body["msgType"] != "E_UPSTREAM" {
+		t.Errorf("renderer lost its code %v", body)
+	}
+
+	// unknown errors are left to ErrorHandler
+	if status, _ = send("/orders/500", "acme"); status != fiber.StatusInternalServerError {
+		t.Errorf("unexpected status of unknown error %d", status)
+	}
+}
@@ -0,0 +1,203 @@
+package reqresp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+type (
+	// Page is the envelope of lists, it's paginated by limit/offset or by an opaque cursor
+	Page[T any] struct {
+		Base
+		Data []T `json:"data" xml:"data>item"`
+		// nil when it's unknown, e.g. with cursor
+		Total  *int64 `json:"total,omitempty" xml:"total,omitempty"`
+		Limit  int    `json:"limit" xml:"limit"`
+		Offset *int   `json:"offset,omitempty" xml:"offset,omitempty"`
+		Cursor string `json:"cursor,omitempty" xml:"cursor,omitempty"`
+		Links  Links  `json:"links" xml:"links"`
+	}
+
+	Links struct {
+		Self string `json:"self" xml:"self"`
+		Next string `json:"next,omitempty" xml:"next,omitempty"`
+		Prev string `json:"prev,omitempty" xml:"prev,omitempty"`
+	}
+
+	// PageQuery is parsed ?limit=&offset=&cursor=&sort=
+	PageQuery struct {
+		Limit  int
+		Offset int
+		Cursor string
+		Sort   []SortField
+	}
+
+	// SortField is an item of ?sort=name,-createdAt, "-" is descending order
+	SortField struct {
+		Field string
+		Desc  bool
+	}
+
+	PageConfig struct {
+		// Optional. Default value 20
+		DefaultLimit int
+		// Optional. Default value 100
+		MaxLimit int
+		// Optional. Default value 10000, deeper lists should use cursor
+		MaxOffset int
+		// Optional. Fields allowed in sort, sort is rejected when it's empty
+		Sort []string
+	}
+)
+
+const (
+	queryLimit  = "limit"
+	queryOffset = "offset"
+	queryCursor = "cursor"
+	querySort   = "sort"
+)
+
+var msgTypePage = "E_PAGE"
+
+func (cfg PageConfig) withDefaults() PageConfig {
+	if cfg.DefaultLimit <= 0 {
+		cfg.DefaultLimit = 20
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = 100
+	}
+	if cfg.MaxOffset <= 0 {
+		cfg.MaxOffset = 10000
+	}
+	return cfg
+}
+
+// ParsePage parses and validates query of the list, the error is *Error with status 400
+func ParsePage(c *fiber.Ctx, cfg PageConfig) (query PageQuery, err error) {
+	cfg = cfg.withDefaults()
+	query.Limit = cfg.DefaultLimit
+
+	if value := c.Query(queryLimit); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit < 1 || query.Limit > cfg.MaxLimit {
+			return query, pageError(fmt.Sprintf("limit must be from 1 to %d", cfg.MaxLimit))
+		}
+	}
+
+	if value := c.Query(queryOffset); value != "" {
+		query.Offset, err = strconv.Atoi(value)
+		if err != nil || query.Offset < 0 || query.Offset > cfg.MaxOffset {
+			return query, pageError(fmt.Sprintf("offset must be from 0 to %d", cfg.MaxOffset))
+		}
+	}
+
+	query.Cursor = c.Query(queryCursor)
+	if query.Cursor != "" && query.Offset > 0 {
+		return query, pageError("offset and cursor can't be used together")
+	}
+
+	if value := c.Query(querySort); value != "" {
+		for _, item := range strings.Split(value, ",") {
+			field := SortField{Field: strings.TrimPrefix(item, "-"), Desc: strings.HasPrefix(item, "-")}
+
+			allowed := false
+			for _, name := range cfg.Sort {
+				allowed = allowed || name == field.Field
+			}
+			if !allowed {
+				return query, pageError("sort by " + field.Field + " isn't allowed")
+			}
+
+			query.Sort = append(query.Sort, field)
+		}
+	}
+
+	return query, nil
+}
+
+func pageError(msg string) *Error {
+	return NewError(fiber.StatusBadRequest, nil, msg, &msgTypePage)
+}
+
+// NewPage is the page of limit/offset pagination, links are built from the request URL
+func NewPage[T any](c *fiber.Ctx, query PageQuery, items []T, total int64) Page[T] {
+	page := newPage(c, query, items)
+	page.Total = &total
+	page.Offset = &query.Offset
+
+	if int64(query.Offset+query.Limit) < total {
+		page.Links.Next = pageLink(c, queryOffset, strconv.Itoa(query.Offset+query.Limit))
+	}
+	if query.Offset > 0 {
+		page.Links.Prev = pageLink(c, queryOffset, strconv.Itoa(max(query.Offset-query.Limit, 0)))
+	}
+
+	return page
+}
+
+// NewCursorPage is the page of cursor pagination, next and prev are cursors of neighbour pages, empty when there is no page
+func NewCursorPage[T any](c *fiber.Ctx, query PageQuery, items []T, next, prev string) Page[T] {
+	page := newPage(c, query, items)
+	page.Cursor = query.Cursor
+
+	if next != "" {
+		page.Links.Next = pageLink(c, queryCursor, next)
+	}
+	if prev != "" {
+		page.Links.Prev = pageLink(c, queryCursor, prev)
+	}
+
+	return page
+}
+
+func newPage[T any](c *fiber.Ctx, query PageQuery, items []T) Page[T] {
+	if items == nil {
+		// empty list instead of null
+		items = []T{}
+	}
+
+	return Page[T]{
+		Base:  Base{Success: true},
+		Data:  items,
+		Limit: query.Limit,
+		Links: Links{Self: c.BaseURL() + string(c.Request().URI().RequestURI())},
+	}
+}
+
+// pageLink is the request URL with the replaced query parameter
+func pageLink(c *fiber.Ctx, key, value string) string {
+	args := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(args)
+
+	c.Context().QueryArgs().CopyTo(args)
+	args.Set(key, value)
+
+	return c.BaseURL() + c.Path() + "?" + args.String()
+}
+
+// EncodeCursor makes an opaque cursor from the value, e.g. the sort key of the last item
+func EncodeCursor(value any) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor reads the value of EncodeCursor, the error is *Error with status 400
+func DecodeCursor(cursor string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		err = json.Unmarshal(data, value)
+	}
+	if err != nil {
+		return NewError(fiber.StatusBadRequest, err, "invalid cursor", &msgTypePage)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package reqresp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestPage(t *testing.T) {
+	app := fiber.New()
+	app.Get("/users", func(c *fiber.Ctx) error {
+		query, err := ParsePage(c, PageConfig{MaxLimit: 50, Sort: []string{"name", "createdAt"}})
+		if err != nil {
+			return c.Status(err.(*Error).StatusCode).JSON(err)
+		}
+
+		if query.Cursor != "" {
+			var after int
+			if err := DecodeCursor(query.Cursor, &after); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(err)
+			}
+			return c.JSON(NewCursorPage(c, query, []int{after + 1}, "", query.Cursor))
+		}
+
+		return c.JSON(NewPage(c, query, []string{"a", "b"}, 45))
+	})
+
+	get := func(target string) (int, map[string]any) {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+
+		var result map[string]any
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, result
+	}
+
+	status, page := get("/users?limit=20&offset=20&sort=-createdAt,name&q=x")
+	links := page["links"].(map[string]any)
+	if status != 200 || page["total"] != 45.0 || page["offset"] != 20.0 ||
+		links["next"] != "http://example.com/users?limit=20&offset=40&sort=-createdAt%2Cname&q=x" ||
+		links["prev"] != "http://example.com/users?limit=20&offset=0&sort=-createdAt%2Cname&q=x" {
+		t.Errorf("unexpected page: %d %v", status, page)
+	}
+
+	cursor, _ := EncodeCursor(41)
+	status, page = get("/users?cursor=" + cursor)
+	links = page["links"].(map[string]any)
+	if status != 200 || page["total"] != nil || page["limit"] != 20.0 || links["next"] != nil || links["prev"] == nil {
+		t.Errorf("unexpected cursor page: %d %v", status, page)
+	}
+
+	for _, target := range []string{"/users?limit=51", "/users?offset=-1", "/users?sort=password", "/users?cursor=x&offset=1", "/users?cursor=!"} {
+		if status, body := get(target); status != fiber.StatusBadRequest || body["success"] != false {
+			t.Errorf("%s: expected 400, got %d %v", target, status, body)
+		}
+	}
+}
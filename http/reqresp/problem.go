@@ -0,0 +1,162 @@
+package reqresp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type (
+	// ErrorFormat selects rendering of Error
+	ErrorFormat string
+
+	ProblemConfig struct {
+		// Optional. Default value ErrorFormatNegotiate
+		Format ErrorFormat
+		// Base URI of problem types, the type is TypeBase + msgType, "about:blank" when it's empty
+		TypeBase string
+		// Member name of the trace id, the interceptor sets the trace key of its schema
+		// Optional. Default value "trace_id"
+		TraceIDKey string
+	}
+
+	// Problem is RFC 7807 problem details with extension members
+	Problem struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail,omitempty"`
+		Instance string `json:"instance,omitempty"`
+		Code     string `json:"code,omitempty"`
+		// written with TraceIDKey name
+		TraceID    string       `json:"-"`
+		TraceIDKey string       `json:"-"`
+		Errors     []FieldError `json:"errors,omitempty"`
+	}
+
+	// FieldError is a validation error of the request field, Path is in the notation of json, e.g. "items[0].name"
+	FieldError struct {
+		Path    string `json:"path" xml:"path"`
+		Rule    string `json:"rule" xml:"rule"`
+		Message string `json:"message" xml:"message"`
+	}
+)
+
+const (
+	// ErrorFormatNegotiate renders Problem when the client accepts application/problem+json, envelope otherwise
+	ErrorFormatNegotiate ErrorFormat = ""
+	// ErrorFormatEnvelope renders {success,msg,msgType}
+	ErrorFormatEnvelope ErrorFormat = "envelope"
+	// ErrorFormatProblem renders RFC 7807
+	ErrorFormatProblem ErrorFormat = "problem"
+
+	MIMEApplicationProblemJSON = "application/problem+json"
+
+	localsProblemConfig = "reqresp.problem"
+	localsError         = "reqresp.error"
+
+	defaultTraceIDKey = "trace_id"
+)
+
+// ParseErrorFormat parses the format of config, "negotiate" is the same as empty value
+func ParseErrorFormat(value string) (ErrorFormat, error) {
+	switch format := ErrorFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case ErrorFormatNegotiate, ErrorFormatEnvelope, ErrorFormatProblem:
+		return format, nil
+	case "negotiate":
+		return ErrorFormatNegotiate, nil
+	}
+	return "", fmt.Errorf("unknown error format %q, expected negotiate, envelope or problem", value)
+}
+
+// UseProblemConfig sets rendering of errors for the request, it's called by the interceptor
+func UseProblemConfig(c *fiber.Ctx, cfg ProblemConfig) {
+	c.Locals(localsProblemConfig, cfg)
+}
+
+// SendError writes the error in the format of the request, status 500 is used when it's not set
+func SendError(c *fiber.Ctx, e *Error) error {
+	cfg, _ := c.Locals(localsProblemConfig).(ProblemConfig)
+	c.Locals(localsError, e)
+
+	status := e.StatusCode
+	if status == 0 {
+		status = fiber.StatusInternalServerError
+	}
+	c.Status(status)
+
+	problem := cfg.Format == ErrorFormatProblem ||
+		(cfg.Format == ErrorFormatNegotiate && c.Accepts(fiber.MIMEApplicationJSON, MIMEApplicationProblemJSON) == MIMEApplicationProblemJSON)
+	if !problem {
+		return Send(c, e)
+	}
+
+	traceID, _ := c.UserContext().Value(trace_context.TraceKeyInCtx).(string)
+
+	details := e.Problem(cfg.TypeBase, c.OriginalURL(), traceID)
+	details.TraceIDKey = cfg.TraceIDKey
+
+	return c.JSON(details, MIMEApplicationProblemJSON)
+}
+
+// SentError returns the error written by SendError for the request, e.g. to report its cause
+func SentError(c *fiber.Ctx) *Error {
+	e, _ := c.Locals(localsError).(*Error)
+	return e
+}
+
+// Problem converts the error to RFC 7807, msgType is the code and the last segment of the type
+func (e *Error) Problem(typeBase, instance, traceID string) Problem {
+	status := e.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: instance,
+		TraceID:  traceID,
+		Errors:   e.Fields,
+	}
+
+	if e.Msg != nil {
+		problem.Detail = *e.Msg
+	}
+	if e.MsgType != nil {
+		problem.Code = *e.MsgType
+		if typeBase != "" {
+			problem.Type = typeBase + *e.MsgType
+		}
+	}
+
+	return problem
+}
+
+// MarshalJSON writes TraceID with TraceIDKey name
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+
+	data, err := json.Marshal(problem(p))
+	if err != nil || p.TraceID == "" {
+		return data, err
+	}
+
+	key := p.TraceIDKey
+	if key == "" {
+		key = defaultTraceIDKey
+	}
+
+	member, err := json.Marshal(map[string]string{key: p.TraceID})
+	if err != nil {
+		return nil, err
+	}
+
+	// {...} + ,"key":"value"}
+	return append(append(data[:len(data)-1], ','), member[1:]...), nil
+}
@@ -0,0 +1,112 @@
+package reqresp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+func TestSendError(t *testing.T) {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(trace_context.SetTraceID(context.Background(), "abc"))
+		UseProblemConfig(c, ProblemConfig{Format: ErrorFormat(c.Query("format")), TypeBase: "https://errors.example.com/"})
+		return c.Next()
+	})
+	app.Post("/users", func(c *fiber.Ctx) error {
+		msgType := "E_VALIDATION"
+		e := NewError(fiber.StatusUnprocessableEntity, nil, "invalid user", &msgType)
+		e.Fields = []FieldError{{Path: "email", Rule: "email", Message: "must be an email"}}
+		return SendError(c, e)
+	})
+
+	send := func(target, accept string) (string, map[string]any) {
+		req := httptest.NewRequest(fiber.MethodPost, target, nil)
+		req.Header.Set(fiber.HeaderAccept, accept)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnprocessableEntity {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+		body, _ := io.ReadAll(resp.Body)
+
+		var result map[string]any
+		if err := json.Unmarshal(body, &result); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Header.Get(fiber.HeaderContentType), result
+	}
+
+	ctype, body := send("/users", fiber.MIMEApplicationJSON)
+	if ctype != fiber.MIMEApplicationJSON || body["msgType"] != "E_VALIDATION" || body["fields"] == nil {
+		t.Errorf("expected envelope: %s %v", ctype, body)
+	}
+
+	// q-values are respected
+	for _, accept := range []string{MIMEApplicationProblemJSON + ";q=0, application/json", "application/json, " + MIMEApplicationProblemJSON + ";q=0.5"} {
+		if ctype, body = send("/users", accept); ctype != fiber.MIMEApplicationJSON || body["msgType"] != "E_VALIDATION" {
+			t.Errorf("%s: expected envelope: %s %v", accept, ctype, body)
+		}
+	}
+
+	for _, c := range []struct{ target, accept string }{
+		{"/users", MIMEApplicationProblemJSON + ", application/json"},
+		{"/users?format=problem", fiber.MIMEApplicationJSON},
+	} {
+		ctype, body = send(c.target, c.accept)
+		errs, _ := body["errors"].([]any)
+		if ctype != MIMEApplicationProblemJSON || body["type"] != "https://errors.example.com/E_VALIDATION" ||
+			body["title"] != "Unprocessable Entity" || body["status"] != 422.0 || body["detail"] != "invalid user" ||
+			body["instance"] != c.target || body["trace_id"] != "abc" || len(errs) != 1 {
+			t.Errorf("expected problem: %s %v", ctype, body)
+		}
+	}
+}
+
+func TestProblemTraceIDKey(t *testing.T) {
+	problem := NewError(fiber.StatusNotFound, nil, "not found", nil).Problem("", "/users/1", "abc")
+
+	for key, expected := range map[string]string{"": "trace_id", "trace.id": "trace.id"} {
+		problem.TraceIDKey = key
+
+		data, err := json.Marshal(problem)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var body map[string]any
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatalf("%v: %s", err, data)
+		}
+		if body[expected] != "abc" || len(body) != 6 {
+			t.Errorf("unexpected problem %s", data)
+		}
+	}
+}
+
+func TestParseErrorFormat(t *testing.T) {
+	for value, expected := range map[string]ErrorFormat{"": ErrorFormatNegotiate, "negotiate": ErrorFormatNegotiate, "Problem": ErrorFormatProblem, "envelope": ErrorFormatEnvelope} {
+		if format, err := ParseErrorFormat(value); err != nil || format != expected {
+			t.Errorf("%q: unexpected format %q %v", value, format, err)
+		}
+	}
+
+	if _, err := ParseErrorFormat("problems"); err == nil {
+		t.Error("expected error of unknown format")
+	}
+}
+
+func TestFieldErrorShape(t *testing.T) {
+	// the shape is shared by the envelope and problem details
+	data, _ := json.Marshal(FieldError{Path: "items[0].name", Rule: "required", Message: "is required"})
+	if string(data) != `{"path":"items[0].name","rule":"required","message":"is required"}` {
+		t.Errorf("unexpected shape %s", data)
+	}
+}
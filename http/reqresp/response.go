@@ -4,20 +4,27 @@ import "fmt"
 
 type (
 	Base struct {
-		Success bool `json:"success"`
+		Success bool `json:"success" xml:"success"`
 	}
 
 	Data[T any] struct {
 		Base
-		Data T `json:"data,omitempty"`
+		Data T `json:"data,omitempty" xml:"data,omitempty"`
 	}
 
 	Error struct {
 		Base
-		Msg        *string `json:"msg,omitempty"`
-		MsgType    *string `json:"msgType,omitempty"`
-		LogError   error   `json:"-"`
-		StatusCode int     `json:"-"`
+		Msg        *string      `json:"msg,omitempty" xml:"msg,omitempty"`
+		MsgType    *string      `json:"msgType,omitempty" xml:"msgType,omitempty"`
+		Fields     []FieldError `json:"fields,omitempty" xml:"fields,omitempty"`
+		LogError   error        `json:"-" xml:"-"`
+		StatusCode int          `json:"-" xml:"-"`
+	}
+
+	// Renderer is an error which knows its response, the interceptor renders it automatically,
+	// acceptLanguage is the value of Accept-Language request header
+	Renderer interface {
+		Render(acceptLanguage string) *Error
 	}
 )
 
@@ -64,3 +71,8 @@ func (e Error) Error() string {
 		return ""
 	}
 }
+
+// Render implements Renderer
+func (e *Error) Render(_ string) *Error {
+	return e
+}
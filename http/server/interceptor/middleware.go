@@ -1,10 +1,11 @@
 package interceptor
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-
-	//"goplate/http/reqresp"
+	"goplate/http/reqresp"
 
 	trace_context "github.com/rzaripov1990/trace_ctx"
 
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
 type (
@@ -45,6 +47,11 @@ type (
 		// Optional. Default value true
 		EnableCatchPanic bool
 
+		// Write buffered records of the trace_id (trace_logger.FlightRecorder) on panic or 5xx response
+		//
+		// Optional. Default value true
+		EnableFlightRecorder bool
+
 		// Enable sensitive data masking
 		//
 		// Optional. Default value true
@@ -55,6 +62,33 @@ type (
 
 		// If a slow request is detected, the log level is set to Warning; otherwise, it is set to Debug.
 		SlowRequestDuration time.Duration
+
+		// Attribute keys of request logs (trace_logger.SchemaOTel, trace_logger.SchemaECS)
+		//
+		// Optional. Default value trace_logger.SchemaDefault
+		Schema trace_logger.Schema
+
+		// Rendering of reqresp.Error, RFC 7807 problem+json or the envelope (reqresp.SendError)
+		//
+		// Optional. Default value is negotiation by Accept header
+		Problem reqresp.ProblemConfig
+
+		// Receives panics and 5xx responses (ierror.ReportIncident)
+		//
+		// Optional. Default value nil
+		Report func(ctx context.Context, incident Incident)
+	}
+
+	// Incident is a panic or 5xx response passed to Config.Report
+	Incident struct {
+		Err    error
+		Panic  bool
+		Stack  Stack // captured for panics only
+		Method string
+		Route  string
+		Status int
+		// request body, masked when MaskSensitiveData is enabled
+		Request map[string]any
 	}
 
 	SensitiveData struct {
@@ -69,13 +103,14 @@ type (
 var (
 	// ConfigDefault is the default config
 	configDefault = Config{
-		Log:               nil,
-		ErrorHandler:      nil,
-		EnableLogRequest:  true,
-		EnableLogHeaders:  true,
-		EnableLogResponse: true,
-		EnableCatchPanic:  true,
-		MaskSensitiveData: true,
+		Log:                  nil,
+		ErrorHandler:         nil,
+		EnableLogRequest:     true,
+		EnableLogHeaders:     true,
+		EnableLogResponse:    true,
+		EnableCatchPanic:     true,
+		EnableFlightRecorder: true,
+		MaskSensitiveData:    true,
 		SensitiveData: SensitiveData{
 			DeleteKeyInRequest:  []string{"file", "content"},
 			DeleteKeyInResponse: []string{"bodyB64", "file", "content"},
@@ -93,6 +128,7 @@ var (
 			},
 		},
 		SlowRequestDuration: 5 * time.Second,
+		Schema:              trace_logger.SchemaDefault,
 	}
 
 	sensitiveInRequest  = map[string]bool{}
@@ -100,6 +136,10 @@ var (
 	sensitiveInResponse = map[string]bool{}
 )
 
+const (
+	headerTraceParent = "traceparent"
+)
+
 func is(one []byte, two string) bool {
 	return strings.HasPrefix(string(one), two)
 }
@@ -114,6 +154,20 @@ func New(config ...Config) fiber.Handler {
 		cfg = config[0]
 	}
 
+	if cfg.Schema.Name == "" {
+		cfg.Schema = trace_logger.SchemaDefault
+	}
+	keys := cfg.Schema.Server
+
+	if cfg.Problem.TraceIDKey == "" {
+		cfg.Problem.TraceIDKey = cfg.Schema.TraceID
+	}
+
+	if cfg.Log != nil {
+		// level of the interceptor may be overridden by trace_logger.SetLevel("interceptor", ...)
+		cfg.Log = trace_logger.Component(cfg.Log, "interceptor")
+	}
+
 	if cfg.MaskSensitiveData {
 		for i := range cfg.SensitiveData.InRequest {
 			sensitiveInRequest[cfg.SensitiveData.InRequest[i]] = true
@@ -130,17 +184,31 @@ func New(config ...Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
 
-		// set trace_id
+		// set trace_id, span of the request is a child of W3C traceparent when it's passed
+		traceID, parent, traced := trace_logger.ParseTraceParent(c.Get(headerTraceParent))
+		if !traced {
+			traceID = trace_context.GetTraceID(c.UserContext())
+			parent.Flags = "01"
+		}
+
 		c.SetUserContext(
-			trace_context.SetTraceID(
-				c.UserContext(),
-				c.Get( // from Request headers
-					trace_context.TraceIDKeyName,
-					trace_context.GetTraceID(c.UserContext()),
+			trace_logger.WithSpan(
+				trace_context.SetTraceID(
+					c.UserContext(),
+					c.Get( // from Request headers
+						trace_context.TraceIDKeyName,
+						traceID,
+					),
 				),
+				trace_logger.Span{
+					ID:    trace_logger.NewSpanID(),
+					Flags: parent.Flags,
+				},
 			),
 		)
 
+		reqresp.UseProblemConfig(c, cfg.Problem)
+
 		if cfg.EnableCatchPanic {
 			// catch panics
 			defer func() {
@@ -151,17 +219,18 @@ func New(config ...Config) fiber.Handler {
 
 					if cfg.Log != nil {
 						var slogValues []slog.Attr
-						slogValues = append(
-							slogValues,
-							slog.String("error", panErr.Error()),
-							slog.Any("stacktrace", frames.Print()),
-						)
+						slogValues = trace_logger.Append(slogValues, keys.Error, panErr.Error())
+						slogValues = trace_logger.Append(slogValues, keys.Stacktrace, frames.Print())
 
-						trace_logger.L(c.UserContext(), cfg.Log).LogAttrs(
+						cfg.Schema.L(c.UserContext(), cfg.Log).LogAttrs(
 							c.UserContext(),
 							slog.LevelError,
 							"Request processed",
 							slogValues...)
+
+						if cfg.EnableFlightRecorder {
+							trace_logger.Dump(c.UserContext(), cfg.Log, cfg.Schema)
+						}
 					}
 
 					if cfg.ErrorHandler != nil {
@@ -169,77 +238,43 @@ func New(config ...Config) fiber.Handler {
 					} else {
 						c.Context().Error(panErr.Error(), fiber.StatusInternalServerError)
 					}
+
+					if cfg.Report != nil {
+						cfg.Report(c.UserContext(), Incident{
+							Err:     panErr,
+							Panic:   true,
+							Stack:   frames,
+							Method:  c.Method(),
+							Route:   c.Route().Path,
+							Status:  fiber.StatusInternalServerError,
+							Request: requestSource(c, cfg),
+						})
+					}
 				}
 			}()
 		}
 
 		if cfg.EnableLogRequest && cfg.Log != nil {
-			uri := func() string {
-				s := c.Path()
-				q := c.Request().URI().QueryString()
-				if len(q) > 0 {
-					s += "?" + string(q)
-				}
-				return s
-			}()
-
 			var slogValues []slog.Attr
-			slogValues = append(
-				slogValues,
-				slog.String("method", c.Route().Method),
-				slog.String("query", uri),
-			)
+			slogValues = trace_logger.Append(slogValues, keys.Method, c.Route().Method)
+			slogValues = keys.AppendURL(slogValues, c.Path(), string(c.Request().URI().QueryString()))
 
 			if cfg.EnableLogHeaders {
-				var headers []string
+				headers := make(map[string]string)
 				for k, v := range c.GetReqHeaders() {
 					if !sensitiveInHeader[k] {
-						headers = append(headers, k+"="+strings.Join(v, ", "))
+						headers[k] = strings.Join(v, ", ")
 					}
 				}
 
-				if len(headers) > 0 {
-					slogValues = append(
-						slogValues,
-						slog.String("headers", strings.Join(headers, ";")),
-					)
-				}
+				slogValues = keys.AppendHeaders(slogValues, headers)
 			}
 
-			ctype := c.Request().Header.ContentType()
-			body := c.Request().Body()
-			if len(body) > 0 {
-				var source map[string]any
-
-				if is(ctype, fiber.MIMEApplicationJSON) {
-					_ = json.Unmarshal(body, &source)
-				} else if is(ctype, fiber.MIMEApplicationForm) {
-					parsed := strings.Split(string(body), "&")
-					if len(parsed) > 0 {
-						source = make(map[string]any)
-					}
-					for i := range parsed {
-						part := strings.Split(parsed[i], "=")
-						source[part[0]] = part[1]
-					}
-				}
-
-				if cfg.MaskSensitiveData && source != nil {
-					if len(cfg.SensitiveData.DeleteKeyInRequest) > 0 {
-						DeleteKeys(source)
-					}
-					if len(cfg.SensitiveData.InRequest) > 0 {
-						MaskSensitiveKeys(source, sensitiveInRequest)
-					}
-				}
-
-				slogValues = append(
-					slogValues,
-					slog.Any("body", source),
-				)
+			if len(c.Request().Body()) > 0 {
+				slogValues = trace_logger.Append(slogValues, keys.RequestBody, requestSource(c, cfg))
 			}
 
-			trace_logger.L(c.UserContext(), cfg.Log).LogAttrs(
+			cfg.Schema.L(c.UserContext(), cfg.Log).LogAttrs(
 				c.UserContext(),
 				slog.LevelDebug,
 				"Request",
@@ -254,6 +289,13 @@ func New(config ...Config) fiber.Handler {
 
 		// go to next handler
 		err := c.Next()
+		handlerErr := err
+
+		// errors which know their response are rendered there, other errors go to ErrorHandler
+		var renderer reqresp.Renderer
+		if errors.As(err, &renderer) {
+			err = reqresp.SendError(c, renderer.Render(c.Get(fiber.HeaderAcceptLanguage)))
+		}
 
 		if err != nil {
 			errFound = true
@@ -265,20 +307,19 @@ func New(config ...Config) fiber.Handler {
 			ctype := c.Response().Header.ContentType()
 
 			var slogValues []slog.Attr
-			slogValues = append(
-				slogValues,
-				slog.String("content-type", string(ctype)),
-				slog.Int("code", c.Response().StatusCode()),
-				slog.Duration("duration_nanosec", duration),
-				slog.String("duration", duration.String()),
-			)
+			slogValues = trace_logger.Append(slogValues, keys.ContentType, string(ctype))
+			slogValues = trace_logger.Append(slogValues, keys.StatusCode, c.Response().StatusCode())
+			slogValues = keys.AppendDuration(slogValues, duration)
+
+			if cfg.EnableLogRequest {
+				if payload := payloadSource(c, cfg); payload != nil {
+					slogValues = trace_logger.Append(slogValues, keys.Payload, payload)
+				}
+			}
 
 			slow := cfg.SlowRequestDuration > 0 && duration.Seconds() > cfg.SlowRequestDuration.Seconds()
 			if slow {
-				slogValues = append(
-					slogValues,
-					slog.Bool("slow", true),
-				)
+				slogValues = trace_logger.Append(slogValues, keys.Slow, true)
 			}
 
 			// when err is nil, get response body
@@ -289,8 +330,8 @@ func New(config ...Config) fiber.Handler {
 			if len(body) > 0 {
 				var source map[string]any
 
-				if is(ctype, fiber.MIMEApplicationJSON) {
-					_ = json.Unmarshal(body, &source)
+				if codec, ok := reqresp.CodecFor(string(ctype)); ok {
+					_ = codec.Unmarshal(body, &source)
 				}
 
 				if cfg.MaskSensitiveData && source != nil {
@@ -302,13 +343,10 @@ func New(config ...Config) fiber.Handler {
 					}
 				}
 
-				slogValues = append(
-					slogValues,
-					slog.Any("response", source),
-				)
+				slogValues = trace_logger.Append(slogValues, keys.ResponseBody, source)
 			}
 
-			trace_logger.L(c.UserContext(), cfg.Log).LogAttrs(
+			cfg.Schema.L(c.UserContext(), cfg.Log).LogAttrs(
 				c.UserContext(),
 				func() slog.Level {
 					if slow {
@@ -322,13 +360,101 @@ func New(config ...Config) fiber.Handler {
 			)
 		}
 
-		if errFound && cfg.ErrorHandler != nil {
-			cfg.ErrorHandler(c, err)
-			return nil
-		} else {
-			c.Context().Error(err.Error(), fiber.StatusInternalServerError)
+		if errFound {
+			if cfg.ErrorHandler != nil {
+				cfg.ErrorHandler(c, err)
+				err = nil
+			} else {
+				c.Context().Error(err.Error(), fiber.StatusInternalServerError)
+			}
+		}
+
+		if status := c.Response().StatusCode(); status >= fiber.StatusInternalServerError {
+			if cfg.EnableFlightRecorder && cfg.Log != nil {
+				trace_logger.Dump(c.UserContext(), cfg.Log, cfg.Schema)
+			}
+
+			if cfg.Report != nil {
+				if e := reqresp.SentError(c); handlerErr == nil && e != nil && e.LogError != nil {
+					handlerErr = e.LogError
+				}
+				if handlerErr == nil {
+					handlerErr = errors.New(utils.StatusMessage(status))
+				}
+
+				cfg.Report(c.UserContext(), Incident{
+					Err:     handlerErr,
+					Method:  c.Method(),
+					Route:   c.Route().Path,
+					Status:  status,
+					Request: requestSource(c, cfg),
+				})
+			}
 		}
 
 		return err
 	}
 }
+
+// requestSource parses the request body by reqresp codecs or as form, sensitive data is masked when it's enabled
+func requestSource(c *fiber.Ctx, cfg Config) map[string]any {
+	ctype := c.Request().Header.ContentType()
+	body := c.Request().Body()
+	if len(body) == 0 {
+		return nil
+	}
+
+	var source map[string]any
+
+	if codec, ok := reqresp.CodecFor(string(ctype)); ok {
+		_ = codec.Unmarshal(body, &source)
+	} else if is(ctype, fiber.MIMEApplicationForm) {
+		parsed := strings.Split(string(body), "&")
+		if len(parsed) > 0 {
+			source = make(map[string]any)
+		}
+		for i := range parsed {
+			part := strings.SplitN(parsed[i], "=", 2)
+			if len(part) == 2 {
+				source[part[0]] = part[1]
+			} else {
+				source[part[0]] = ""
+			}
+		}
+	}
+
+	return maskRequest(source, cfg)
+}
+
+// payloadSource is the value of reqresp.Bind masked as the request body
+func payloadSource(c *fiber.Ctx, cfg Config) map[string]any {
+	payload := reqresp.Payload(c)
+	if payload == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+
+	var source map[string]any
+	if err := json.Unmarshal(data, &source); err != nil {
+		return nil
+	}
+
+	return maskRequest(source, cfg)
+}
+
+func maskRequest(source map[string]any, cfg Config) map[string]any {
+	if cfg.MaskSensitiveData && source != nil {
+		if len(cfg.SensitiveData.DeleteKeyInRequest) > 0 {
+			DeleteKeys(source)
+		}
+		if len(cfg.SensitiveData.InRequest) > 0 {
+			MaskSensitiveKeys(source, sensitiveInRequest)
+		}
+	}
+
+	return source
+}
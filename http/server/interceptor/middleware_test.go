@@ -0,0 +1,81 @@
+package interceptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"goplate/http/reqresp"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestPayloadMasking(t *testing.T) {
+	out := &syncBuffer{}
+
+	cfg := configDefault
+	cfg.Log = slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	app := fiber.New()
+	app.Use(New(cfg))
+	app.Post("/users/:id", func(c *fiber.Ctx) error {
+		type request struct {
+			ID       int    `params:"id"`
+			Login    string `json:"login"`
+			Password string `json:"password"`
+		}
+
+		req, err := reqresp.Bind[request](c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(reqresp.NewData(req.ID))
+	})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/users/7", strings.NewReader(`{"login":"bob","password":"secret123"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := out.String()
+	if strings.Contains(logs, "secret123") {
+		t.Fatalf("password must be masked: %s", logs)
+	}
+
+	var payload map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+		if value, ok := record[cfg.Schema.Server.Payload].(map[string]any); ok {
+			payload = value
+		}
+	}
+
+	if payload == nil || payload["login"] != "bob" || payload["ID"] != 7.0 ||
+		!strings.HasPrefix(payload["password"].(string), "*") {
+		t.Errorf("unexpected payload %v: %s", payload, logs)
+	}
+}
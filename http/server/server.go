@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"goplate/env"
 	"goplate/http/reqresp"
+	"goplate/pkg/trace_logger"
 	"log/slog"
+	"net"
 	"os"
 	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,6 +21,13 @@ type (
 		cfg *env.BaseConfig
 		App *fiber.App
 		log *slog.Logger
+
+		// readiness, it's set when the server listens and reset by Drain
+		ready atomic.Bool
+		// listener is used instead of PORT, e.g. inherited from the parent process
+		listener net.Listener
+		// readiness of the application besides the server, e.g. graceful startup
+		readiness func() bool
 	}
 
 	healthCheck struct {
@@ -30,6 +40,7 @@ type (
 		CPUNum        int    `json:"cpuNum"`
 		MemoryUsage   string `json:"memoryUsage"`
 		GoroutineNum  int    `json:"goroutineNum"`
+		Ready         bool   `json:"ready"`
 	}
 )
 
@@ -40,13 +51,42 @@ var (
 
 func (fs *FiberServer) Run() error {
 	uptime = time.Now()
-	fs.log.Info("Server started...", "port", fs.cfg.Port)
-	err := fs.App.Listen(":" + fs.cfg.Port)
-	if err != nil {
-		fs.log.Error(err.Error())
-		panic(err)
+	if fs.listener != nil {
+		fs.log.Info("Server started...", "addr", fs.listener.Addr().String())
+		return fs.App.Listener(fs.listener)
 	}
-	return err
+
+	fs.log.Info("Server started...", "port", fs.cfg.Port)
+	// error is returned to the caller, e.g. the port is busy
+	return fs.App.Listen(":" + fs.cfg.Port)
+}
+
+// WithListener serves the existing listener instead of listening PORT
+func (fs *FiberServer) WithListener(listener net.Listener) *FiberServer {
+	fs.listener = listener
+	return fs
+}
+
+// Ready reports readiness of the server to accept traffic
+func (fs *FiberServer) Ready() bool {
+	return fs.ready.Load() && (fs.readiness == nil || fs.readiness())
+}
+
+// WithReadiness adds the check to readiness of the server, e.g. graceful.CloseGroup.IsReady
+func (fs *FiberServer) WithReadiness(check func() bool) *FiberServer {
+	fs.readiness = check
+	return fs
+}
+
+// SetReady changes readiness reported by /ready
+func (fs *FiberServer) SetReady(ready bool) {
+	fs.ready.Store(ready)
+}
+
+// Drain fails readiness, the server keeps serving until Close
+func (fs *FiberServer) Drain() {
+	fs.SetReady(false)
+	fs.log.Info("Server draining")
 }
 
 func (fs *FiberServer) Close(ctx ...context.Context) error {
@@ -76,15 +116,24 @@ func New(
 	}
 
 	app := fiber.New(fiberConfig)
+	// fasthttp server logs only errors
+	app.Server().Logger = trace_logger.NewPrintfLogger(log, slog.LevelError)
 	for i := 0; i < len(middlewares); i++ {
 		app.Use(middlewares[i])
 	}
 
-	return &FiberServer{
+	fs := &FiberServer{
 		App: app,
 		cfg: cfg,
 		log: log,
 	}
+
+	app.Hooks().OnListen(func(fiber.ListenData) error {
+		fs.SetReady(true)
+		return nil
+	})
+
+	return fs
 }
 
 func (fs *FiberServer) WithDefaultRouters() *FiberServer {
@@ -99,10 +148,74 @@ func (fs *FiberServer) WithDefaultRouters() *FiberServer {
 			health.Uptime = time.Since(uptime).String()
 			health.MemoryUsage = fmt.Sprintf("%dMB", memStats.Alloc/1024/1024)
 			health.GoroutineNum = runtime.NumGoroutine()
+			health.Ready = fs.Ready()
 
 			return c.JSON(reqresp.NewData(health))
 		},
 	)
 
+	// readiness endpoint, fails while the server is starting or draining
+	fs.App.All("/ready",
+		func(c *fiber.Ctx) error {
+			if !fs.Ready() {
+				return reqresp.SendError(c, reqresp.NewError(fiber.StatusServiceUnavailable, nil, "not ready", nil))
+			}
+
+			return c.JSON(reqresp.NewData(map[string]bool{"ready": true}))
+		},
+	)
+
+	return fs
+}
+
+// WithJobsRouters registers the admin endpoint with the state of scheduled jobs, e.g. scheduler.Jobs
+func (fs *FiberServer) WithJobsRouters(jobs func() any) *FiberServer {
+	fs.App.Get("/admin/jobs",
+		func(c *fiber.Ctx) error {
+			return c.JSON(reqresp.NewData(jobs()))
+		},
+	)
+
+	return fs
+}
+
+// WithDebugRouters registers diagnostic endpoints, don't expose them in production
+func (fs *FiberServer) WithDebugRouters() *FiberServer {
+	// buffered records of trace_logger.FlightRecorder
+	fs.App.Get("/debug/logs/:trace_id",
+		func(c *fiber.Ctx) error {
+			entries := trace_logger.Records(c.Params("trace_id"))
+			if entries == nil {
+				return reqresp.SendError(c, reqresp.NewError(fiber.StatusNotFound, nil, "trace_id not found", nil))
+			}
+
+			return c.JSON(reqresp.NewData(entries))
+		},
+	)
+
+	// levels of trace_logger components, PUT body is {"http.client": "debug", "": "info"}
+	fs.App.Get("/debug/log/levels",
+		func(c *fiber.Ctx) error {
+			return c.JSON(reqresp.NewData(trace_logger.GetLevels()))
+		},
+	)
+
+	fs.App.Put("/debug/log/levels",
+		func(c *fiber.Ctx) error {
+			var levels map[string]string
+			if err := c.BodyParser(&levels); err != nil {
+				return reqresp.SendError(c, reqresp.NewError(fiber.StatusBadRequest, err, err, nil))
+			}
+
+			for name, level := range levels {
+				if err := trace_logger.SetLevel(name, level); err != nil {
+					return reqresp.SendError(c, reqresp.NewError(fiber.StatusBadRequest, err, err, nil))
+				}
+			}
+
+			return c.JSON(reqresp.NewData(trace_logger.GetLevels()))
+		},
+	)
+
 	return fs
 }
@@ -0,0 +1,199 @@
+package graceful
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+type (
+	CloseStatus string
+
+	// CloseResult describes how the closer finished, it's a part of the shutdown summary
+	CloseResult struct {
+		Name     string
+		Phase    Phase
+		Status   CloseStatus
+		Duration time.Duration
+		Err      error
+		// stack of the stuck closer goroutine when it's timed out
+		Goroutine string
+	}
+
+	// CloseError is passed to OnError when the closer is failed or timed out
+	CloseError struct {
+		CloseResult
+	}
+)
+
+const (
+	CloseFinished CloseStatus = "finished"
+	CloseFailed   CloseStatus = "failed"
+	CloseTimeout  CloseStatus = "timeout"
+)
+
+func (ce *CloseError) Error() string {
+	if ce.Status == CloseTimeout {
+		return fmt.Sprintf("close %s (phase %d): timeout after %s", ce.Name, ce.Phase, ce.Duration)
+	}
+	return fmt.Sprintf("close %s (phase %d): %v", ce.Name, ce.Phase, ce.Err)
+}
+
+func (ce *CloseError) Unwrap() error {
+	return ce.Err
+}
+
+// Summary returns results of all closers after Wait
+func (group *CloseGroup) Summary() []CloseResult {
+	group.summaryMu.Lock()
+	defer group.summaryMu.Unlock()
+
+	return append([]CloseResult(nil), group.summary...)
+}
+
+func (group *CloseGroup) closePhase(ctx context.Context, phase Phase) {
+	if timeout := group.phaseTimeouts[phase]; timeout > 0 {
+		withTimeout, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		ctx = withTimeout
+	}
+
+	// buffered, so abandoned closers don't block
+	done := make(chan CloseResult, len(group.closer))
+	count := 0
+
+	// run closers of the phase as goroutines in parallel
+	for _, closer := range group.closer {
+		if closer.phase == phase {
+			count++
+			go group.runCloser(ctx, closer, done)
+		}
+	}
+
+	// every closer reports, at least when its context is done
+	for ; count > 0; count-- {
+		result := <-done
+
+		var err *CloseError
+
+		group.summaryMu.Lock()
+		group.summary = append(group.summary, result)
+		if result.Status != CloseFinished {
+			err = &CloseError{CloseResult: result}
+			group.closeErrors = append(group.closeErrors, err)
+		}
+		group.summaryMu.Unlock()
+
+		if err != nil {
+			group.onError.Func(ctx, group.onError.Handler, err)
+		}
+	}
+}
+
+func (group *CloseGroup) runCloser(ctx context.Context, c closer, done chan CloseResult) {
+	if c.timeout > 0 {
+		withTimeout, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		ctx = withTimeout
+	}
+
+	var (
+		start     = time.Now()
+		errors    = make(chan error, 1)
+		goroutine = make(chan uint64, 1)
+	)
+
+	go func() {
+		goroutine <- goroutineID()
+		c.task(ctx, c.resource, c.close, errors)
+	}()
+
+	result := CloseResult{
+		Name:  c.name,
+		Phase: c.phase,
+	}
+
+	select {
+	case err := <-errors:
+		result.Duration = time.Since(start)
+		result.Err = err
+		result.Status = CloseFinished
+		if err != nil {
+			result.Status = CloseFailed
+		}
+	case <-ctx.Done():
+		result.Duration = time.Since(start)
+		result.Err = ctx.Err()
+		result.Status = CloseTimeout
+		result.Goroutine = goroutineStack(<-goroutine)
+	}
+
+	done <- result
+}
+
+func (group *CloseGroup) logSummary(ctx context.Context) {
+	results := group.Summary()
+	if len(results) == 0 {
+		return
+	}
+
+	level := slog.LevelInfo
+	closers := make([]map[string]any, len(results))
+
+	for i, result := range results {
+		closers[i] = map[string]any{
+			"name":     result.Name,
+			"phase":    int(result.Phase),
+			"status":   string(result.Status),
+			"duration": result.Duration.String(),
+		}
+		if result.Err != nil {
+			closers[i]["error"] = result.Err.Error()
+		}
+		if result.Status != CloseFinished {
+			level = slog.LevelWarn
+		}
+	}
+
+	handlerLogger(group.onError.Handler).Log(ctx, level, "Shutdown summary", "closers", closers)
+}
+
+// goroutineID parses the id from the header of the current goroutine stack: "goroutine 42 [running]:"
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(buf[:i]), 10, 64)
+		return id
+	}
+	return 0
+}
+
+// goroutineStack returns the stack of the goroutine by its id from the dump of all goroutines
+func goroutineStack(id uint64) string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(block, header) {
+			return string(block)
+		}
+	}
+	return ""
+}
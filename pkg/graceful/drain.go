@@ -0,0 +1,65 @@
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PreStop option for Prepare, on shutdown the group runs Drain tasks and waits Delay
+// while processes are still serving, only then closers run. The delay is skipped when a process failed
+type PreStop struct {
+	Delay time.Duration
+}
+
+// Drain registers a task which runs first on shutdown, e.g. fails readiness of the server.
+// Options: Name (default is the type of resource)
+func Drain[T_resource any](group *CloseGroup, resource T_resource, taskDrain Task[T_resource], options ...any) {
+	name := fmt.Sprintf("%T", resource)
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Name:
+			name = typed.Value
+		}
+	}
+
+	group.drainers = append(group.drainers, closer{
+		name:     name,
+		resource: resource,
+		close:    taskDrain,
+		task: func(ctx context.Context, data, drain any, errors chan error) {
+			errors <- drain.(Task[T_resource])(ctx, data.(T_resource))
+		},
+	})
+}
+
+func (group *CloseGroup) drain(ctx context.Context) {
+	for _, drainer := range group.drainers {
+		errors := make(chan error, 1)
+		drainer.task(ctx, drainer.resource, drainer.close, errors)
+
+		if err := <-errors; err != nil {
+			group.onError.Func(ctx, group.onError.Handler, fmt.Errorf("drain %s: %w", drainer.name, err))
+		}
+	}
+
+	group.processMu.Lock()
+	failed := len(group.processErrors) > 0
+	group.processMu.Unlock()
+
+	if group.preStop.Delay <= 0 || failed {
+		return
+	}
+
+	handlerLogger(group.onError.Handler).InfoContext(ctx, "Draining", "delay", group.preStop.Delay.String())
+
+	timer := time.NewTimer(group.preStop.Delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
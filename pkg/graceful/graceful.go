@@ -4,10 +4,15 @@ package graceful
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"sort"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -21,22 +26,84 @@ type (
 		Func    func(ctx context.Context, handler any, err error)
 	}
 
+	// Phase orders closers: phases run one by one from the lowest, closers of a phase run in parallel.
+	// Any value may be used as a priority between the predefined phases
+	Phase int
+
+	// PhaseTimeout option for Prepare, the timeout budget of the phase within the timeout of Wait
+	PhaseTimeout struct {
+		Phase   Phase
+		Timeout time.Duration
+	}
+
 	CloseGroup struct {
 		shutdownCtx context.Context //nolint:containedctx
 		cancel      context.CancelFunc
 
-		closer []closer
-		errors chan error
+		closer   []closer
+		drainers []closer
+		preStop  PreStop
+
+		reloadMu     sync.Mutex
+		reloadOnce   sync.Once
+		reloaders    []closer
+		reloadNotify ReloadNotify
+
+		upgrade    Upgrade
+		listenerMu sync.Mutex
+		listeners  []groupListener
+
+		openMu      sync.Mutex
+		openers     []opener
+		opened      atomic.Bool
+		readyCalled atomic.Bool
+		ready       atomic.Bool
 
-		onError OnError
+		health     Health
+		stopNotify context.CancelFunc
 
-		wait atomic.Int32
+		leakCheck *LeakCheck
+		snapshot  Snapshot
+
+		processes     sync.WaitGroup
+		processMu     sync.Mutex
+		processErrors []error
+
+		onError       OnError
+		phaseTimeouts map[Phase]time.Duration
+
+		summaryMu   sync.Mutex
+		summary     []CloseResult
+		closeErrors []error
+	}
+
+	// ShutdownTimeout option for Run, the timeout of Wait
+	ShutdownTimeout struct {
+		Value time.Duration
+	}
+
+	// ExitCoder is an error with own exit code for Run
+	ExitCoder interface {
+		ExitCode() int
+	}
+
+	// Name option for Close, used in diagnostics, default is the type of resource
+	Name struct {
+		Value string
+	}
+
+	// Timeout option for Close, the closer is abandoned as timed out after it
+	Timeout struct {
+		Value time.Duration
 	}
 
 	closer struct {
+		name     string
 		resource any
 		close    any
 		task     task
+		phase    Phase
+		timeout  time.Duration
 	}
 
 	Task[T_resource any] func(ctx context.Context, resource T_resource) error
@@ -44,26 +111,61 @@ type (
 	task func(ctx context.Context, resource, close any, errors chan error)
 )
 
+const (
+	// PhaseServer stops accepting new requests
+	PhaseServer Phase = 100
+	// PhaseDrain waits for in-flight work
+	PhaseDrain Phase = 200
+	// PhaseResource closes queues, db pools and other resources, default phase of Close
+	PhaseResource Phase = 300
+	// PhaseFlush flushes loggers and reporters
+	PhaseFlush Phase = 400
+)
+
 var (
 	DefaultNotify = Notify{
-		Signals: []os.Signal{os.Interrupt},
+		Signals: []os.Signal{os.Interrupt, syscall.SIGTERM},
 	}
 
+	// Handler is *slog.Logger, when it's nil slog.Default() is used at the moment of error
 	DefaultOnError = OnError{
-		Handler: log.Default(),
+		Handler: nil,
 		Func:    defaultOnError,
 	}
 )
 
-func defaultOnError(_ context.Context, handler any, err error) {
-	handler.(*log.Logger).Println(err)
+func defaultOnError(ctx context.Context, handler any, err error) {
+	log := handlerLogger(handler)
+
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) && closeErr.Goroutine != "" {
+		log.ErrorContext(ctx, "graceful", "error", err.Error(), "goroutine", closeErr.Goroutine)
+		return
+	}
+
+	var leakErr *LeakError
+	if errors.As(err, &leakErr) {
+		log.ErrorContext(ctx, "graceful", "error", "goroutines leaked", "leaks", leakErr.Leaks)
+		return
+	}
+
+	log.ErrorContext(ctx, "graceful", "error", err.Error())
+}
+
+func handlerLogger(handler any) *slog.Logger {
+	log, ok := handler.(*slog.Logger)
+	if !ok || log == nil {
+		log = slog.Default()
+	}
+	return log
 }
 
 func Prepare(ctx context.Context, options ...any) (shutdownCtx context.Context, group *CloseGroup) {
 	// make group and set defaults
 	group = &CloseGroup{
-		errors:  make(chan error),
-		onError: DefaultOnError,
+		onError:       DefaultOnError,
+		phaseTimeouts: make(map[Phase]time.Duration),
+		reloadNotify:  DefaultReloadNotify,
 	}
 
 	notify := DefaultNotify
@@ -75,6 +177,20 @@ func Prepare(ctx context.Context, options ...any) (shutdownCtx context.Context,
 			notify = typed
 		case OnError:
 			group.onError = typed
+		case PhaseTimeout:
+			group.phaseTimeouts[typed.Phase] = typed.Timeout
+		case PreStop:
+			group.preStop = typed
+		case ReloadNotify:
+			group.reloadNotify = typed
+		case Upgrade:
+			group.upgrade = typed.withDefaults()
+		case Health:
+			group.health = typed
+		case LeakCheck:
+			typed = typed.withDefaults()
+			group.leakCheck = &typed
+			group.snapshot = TakeSnapshot()
 		}
 	}
 
@@ -85,17 +201,53 @@ func Prepare(ctx context.Context, options ...any) (shutdownCtx context.Context,
 		group.shutdownCtx, group.cancel = context.WithCancel(ctx)
 	}
 
+	group.notifyUpgrade()
+
+	// keepalives last until closers are done
+	var notifyCtx context.Context
+	notifyCtx, group.stopNotify = context.WithCancel(context.WithoutCancel(ctx))
+	group.watchdog(notifyCtx)
+
 	return group.shutdownCtx, group
 }
 
-func Process[T_resource any](group *CloseGroup, resource T_resource, process Task[T_resource]) {
-	group.wait.Add(1)
+// Process runs the task until shutdown, when it returns the group is cancelled.
+// Options: Supervise restarts the task by the policy, Name is used in logs (default is the type of resource)
+func Process[T_resource any](group *CloseGroup, resource T_resource, process Task[T_resource], options ...any) {
+	var (
+		name      = fmt.Sprintf("%T", resource)
+		supervise *Supervise
+	)
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Supervise:
+			supervise = &typed
+		case Name:
+			name = typed.Value
+		}
+	}
+
+	group.processes.Add(1)
+	if supervise != nil {
+		go superviseProcess(group, name, resource, process, supervise.withDefaults())
+		return
+	}
 	go wrapProcess(group, resource, process)
 }
 
-func Close[T_resource any](group *CloseGroup, resource T_resource, taskClose Task[T_resource]) {
+// Close registers closer of the resource, options: Phase (default PhaseResource), Name, Timeout
+func Close[T_resource any](group *CloseGroup, resource T_resource, taskClose Task[T_resource], options ...any) {
 	// fill closer with data before running goroutine to avoid errors
-	group.closer = append(group.closer, closer{
+	group.closer = append(group.closer, newCloser(resource, taskClose, options...))
+}
+
+// newCloser reads options of Close, it's shared with Open
+func newCloser[T_resource any](resource T_resource, taskClose Task[T_resource], options ...any) closer {
+	c := closer{
+		name:     fmt.Sprintf("%T", resource),
+		phase:    PhaseResource,
 		resource: resource,
 		close:    taskClose,
 		task: func(ctx context.Context, data, close any, errors chan error) {
@@ -103,25 +255,90 @@ func Close[T_resource any](group *CloseGroup, resource T_resource, taskClose Tas
 			// types for cast will be inherited from parent function at compile time
 			errors <- close.(Task[T_resource])(ctx, data.(T_resource))
 		},
-	})
+	}
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Phase:
+			c.phase = typed
+		case Name:
+			c.name = typed.Value
+		case Timeout:
+			c.timeout = typed.Value
+		}
+	}
+
+	return c
 }
 
 func wrapProcess[T_resource any](group *CloseGroup, resource T_resource, process Task[T_resource]) {
+	defer group.processes.Done()
+
 	err := process(group.shutdownCtx, resource)
 
 	// cancel shutdown context if error acquired
+	group.fail(err)
+}
+
+// fail shuts down the group and records err, context.Canceled returned after shutdown is a normal stop
+func (group *CloseGroup) fail(err error) {
+	stopping := group.shutdownCtx.Err() != nil
 	group.cancel()
-	// wait until main goroutine receive error
-	group.errors <- err
+
+	if err != nil && !(stopping && errors.Is(err, context.Canceled)) {
+		group.processMu.Lock()
+		group.processErrors = append(group.processErrors, err)
+		group.processMu.Unlock()
+	}
 }
 
-func wrapClose[T_resource any](ctx context.Context, data, taskClose any, errors chan error) {
-	errors <- taskClose.(Task[T_resource])(ctx, data.(T_resource))
+// Run prepares the group with options, calls main, Start and waits for shutdown.
+// The result is the exit code for os.Exit: 0 on success, ExitCode of ExitCoder error or 1 on any other error
+func Run(main func(ctx context.Context, group *CloseGroup) error, options ...any) int {
+	var timeout time.Duration
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case ShutdownTimeout:
+			timeout = typed.Value
+		}
+	}
+
+	ctx, group := Prepare(context.Background(), options...)
+
+	err := main(ctx, group)
+	if err == nil {
+		err = group.Start(ctx)
+	}
+	if err != nil {
+		// close already registered resources
+		group.onError.Func(ctx, group.onError.Handler, err)
+		group.cancel()
+	}
+
+	return ExitCode(errors.Join(err, group.Wait(timeout)))
+}
+
+// ExitCode maps err to the exit code of the process
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return 1
 }
 
-func (group *CloseGroup) Wait(timeout time.Duration) {
+// Wait closes resources after shutdown and returns joined errors of processes and failed closers
+func (group *CloseGroup) Wait(timeout time.Duration) error {
 	// wait until shutdown context cancelled
 	<-group.shutdownCtx.Done()
+	defer group.stopNotify()
 
 	// shutdown context cancelled there so we need in separate context for closing
 	ctx := context.Background()
@@ -134,22 +351,72 @@ func (group *CloseGroup) Wait(timeout time.Duration) {
 		ctx = withTimeout
 	}
 
-	// run closers as goroutines in parallel
+	group.notify(ctx, "STOPPING=1")
+
+	// fail readiness and let in-flight traffic go before closing
+	group.status(ctx, "draining")
+	group.drain(ctx)
+
+	// phases run one by one, closers of a phase in parallel
+	for _, phase := range group.phases() {
+		group.status(ctx, fmt.Sprintf("closing phase %d", phase))
+		group.closePhase(ctx, phase)
+	}
+
+	group.status(ctx, "stopped")
+
+	group.logSummary(ctx)
+
+	// processes should finish after their resources are closed
+	done := make(chan struct{})
+	go func() {
+		group.processes.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	group.processMu.Lock()
+	defer group.processMu.Unlock()
+
+	for _, err := range group.processErrors {
+		group.onError.Func(ctx, group.onError.Handler, err)
+	}
+
+	group.summaryMu.Lock()
+	defer group.summaryMu.Unlock()
+
+	errs := append(append([]error(nil), group.processErrors...), group.closeErrors...)
+
+	if group.leakCheck != nil {
+		group.stopNotify()
+		if leaks := group.snapshot.Leaks(group.leakCheck.Timeout, group.leakCheck.Ignore...); leaks != nil {
+			err := &LeakError{Leaks: leaks}
+			group.onError.Func(ctx, group.onError.Handler, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (group *CloseGroup) phases() []Phase {
+	var phases []Phase
+	seen := make(map[Phase]bool)
+
 	for _, closer := range group.closer {
-		group.wait.Add(1)
-		go closer.task(ctx, closer.resource, closer.close, group.errors)
-	}
-
-cycle:
-	for group.wait.Add(-1) >= 0 {
-		select {
-		case err := <-group.errors:
-			if err != nil {
-				group.onError.Func(ctx, group.onError.Handler, err)
-			}
-		case <-ctx.Done():
-			// finish if context cancelled faster than all errors processed
-			break cycle
+		if !seen[closer.phase] {
+			seen[closer.phase] = true
+			phases = append(phases, closer.phase)
 		}
 	}
+
+	sort.Slice(phases, func(i, j int) bool {
+		return phases[i] < phases[j]
+	})
+
+	return phases
 }
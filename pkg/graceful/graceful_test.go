@@ -0,0 +1,569 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// envUpgradeChild runs the test binary as the child of Upgrade: serve, hang or fail
+const envUpgradeChild = "GRACEFUL_TEST_UPGRADE"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(envUpgradeChild) {
+	case "":
+		os.Exit(m.Run())
+	case "serve":
+		os.Exit(upgradeChild())
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(1)
+}
+
+// upgradeChild takes the listener of the parent, reports readiness and answers one connection
+func upgradeChild() int {
+	_, group := Prepare(context.Background(), Notify{})
+
+	listener, err := Listen(group, "tcp", "127.0.0.1:0")
+	if err != nil {
+		return 1
+	}
+	defer listener.Close()
+
+	group.Ready()
+
+	_ = listener.(*net.TCPListener).SetDeadline(time.Now().Add(10 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		return 1
+	}
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("child"))
+	return 0
+}
+
+func stop(_ context.Context, _ string) error {
+	return nil
+}
+
+func TestClosePhases(t *testing.T) {
+	_, group := Prepare(context.Background(), Notify{}, PhaseTimeout{Phase: PhaseDrain, Timeout: 20 * time.Millisecond})
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(ctx context.Context, name string) error {
+		if name == "stuck" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		return nil
+	}
+
+	Close(group, "logger", record, PhaseFlush)
+	Close(group, "db", record)
+	Close(group, "stuck", record, PhaseDrain)
+	Close(group, "server", record, PhaseServer)
+
+	Process(group, "main", stop)
+
+	start := time.Now()
+	group.Wait(time.Second)
+
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("phase timeout is not applied")
+	}
+
+	expected := []string{"server", "db", "logger"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestCloseSummary(t *testing.T) {
+	var reported []*CloseError
+	onError := OnError{Func: func(_ context.Context, _ any, err error) {
+		var closeErr *CloseError
+		if errors.As(err, &closeErr) {
+			reported = append(reported, closeErr)
+		}
+	}}
+	_, group := Prepare(context.Background(), Notify{}, onError)
+
+	Close(group, "db", stop, Name{Value: "db"})
+	Close(group, "queue", func(_ context.Context, _ string) error {
+		return errors.New("broken")
+	}, Name{Value: "queue"})
+	// ignores context, it's released after the test
+	stuck := make(chan struct{})
+	t.Cleanup(func() { close(stuck) })
+	Close(group, "stuck", func(_ context.Context, _ string) error {
+		<-stuck
+		return nil
+	}, Name{Value: "stuck"}, Timeout{Value: 20 * time.Millisecond})
+
+	Process(group, "main", stop)
+	err := group.Wait(time.Second)
+
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("close errors must be returned: %v", err)
+	}
+
+	statuses := make(map[string]CloseStatus)
+	for _, result := range group.Summary() {
+		statuses[result.Name] = result.Status
+	}
+	if statuses["db"] != CloseFinished || statuses["queue"] != CloseFailed || statuses["stuck"] != CloseTimeout {
+		t.Fatalf("unexpected summary: %v", statuses)
+	}
+
+	if len(reported) != 2 {
+		t.Fatalf("failed and timed out closers must be reported: %v", reported)
+	}
+	for _, closeErr := range reported {
+		if closeErr.Status == CloseTimeout && !strings.Contains(closeErr.Goroutine, "graceful.TestCloseSummary") {
+			t.Errorf("stuck goroutine is not dumped: %s", closeErr.Goroutine)
+		}
+	}
+}
+
+type exitError struct{}
+
+func (exitError) Error() string { return "exit" }
+
+func (exitError) ExitCode() int { return 3 }
+
+func TestRun(t *testing.T) {
+	errBind := errors.New("address already in use")
+	closed := false
+
+	code := Run(func(ctx context.Context, group *CloseGroup) error {
+		Close(group, "db", func(_ context.Context, _ string) error {
+			closed = true
+			return nil
+		})
+		Process(group, "server", func(_ context.Context, _ string) error {
+			return errBind
+		})
+		return nil
+	}, Notify{}, OnError{Func: func(context.Context, any, error) {}})
+
+	if code != 1 || !closed {
+		t.Errorf("process error must close resources and exit with 1, got %d", code)
+	}
+
+	code = Run(func(ctx context.Context, group *CloseGroup) error {
+		return fmt.Errorf("config: %w", exitError{})
+	}, Notify{}, OnError{Func: func(context.Context, any, error) {}})
+
+	if code != 3 {
+		t.Errorf("expected exit code of ExitCoder, got %d", code)
+	}
+
+	// processes returning ctx.Err() on shutdown are stopped normally
+	code = Run(func(ctx context.Context, group *CloseGroup) error {
+		for _, name := range []string{"consumer", "worker"} {
+			Process(group, name, func(ctx context.Context, _ string) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		}
+		Process(group, "stop", func(_ context.Context, _ string) error {
+			return nil
+		})
+		return nil
+	}, Notify{}, OnError{Func: func(_ context.Context, _ any, err error) {
+		t.Errorf("unexpected error %v", err)
+	}})
+
+	if code != 0 {
+		t.Errorf("cancelled processes must exit with 0, got %d", code)
+	}
+}
+
+func TestSupervise(t *testing.T) {
+	ctx, group := Prepare(context.Background(), Notify{}, OnError{Func: func(context.Context, any, error) {}})
+
+	var (
+		runs    int
+		errPoll = errors.New("poll failed")
+	)
+	Process(group, "poller", func(_ context.Context, _ string) error {
+		runs++
+		return errPoll
+	}, Name{Value: "poller"}, Supervise{
+		Policy:      RestartOnFailure,
+		Backoff:     time.Millisecond,
+		MaxRestarts: 3,
+	})
+
+	finished := make(chan struct{})
+	Process(group, "once", func(_ context.Context, _ string) error {
+		defer close(finished)
+		return nil
+	}, Supervise{Policy: RestartOnFailure})
+
+	err := group.Wait(time.Second)
+	if runs != 4 || !errors.Is(err, errPoll) || !strings.Contains(err.Error(), "poller") {
+		t.Fatalf("group must be shut down after 3 restarts: runs %d, %v", runs, err)
+	}
+
+	<-finished
+	if ctx.Err() == nil {
+		t.Error("shutdown context must be cancelled")
+	}
+
+	// supervised consumer returning ctx.Err() on shutdown isn't a failure
+	code := Run(func(ctx context.Context, group *CloseGroup) error {
+		Process(group, "consumer", func(ctx context.Context, _ string) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, Supervise{Policy: RestartOnFailure})
+		Process(group, "stop", func(_ context.Context, _ string) error {
+			return nil
+		})
+		return nil
+	}, Notify{}, OnError{Func: func(_ context.Context, _ any, err error) {
+		t.Errorf("unexpected error %v", err)
+	}})
+
+	if code != 0 {
+		t.Errorf("cancelled supervised process must exit with 0, got %d", code)
+	}
+}
+
+func TestPreStop(t *testing.T) {
+	_, group := Prepare(context.Background(), Notify{}, PreStop{Delay: 50 * time.Millisecond})
+
+	var drained time.Time
+	Drain(group, "server", func(_ context.Context, _ string) error {
+		drained = time.Now()
+		return nil
+	})
+
+	var closed time.Time
+	Close(group, "server", func(_ context.Context, _ string) error {
+		closed = time.Now()
+		return nil
+	}, PhaseServer)
+
+	group.cancel()
+	group.Wait(time.Second)
+
+	if drained.IsZero() || closed.Sub(drained) < 50*time.Millisecond {
+		t.Errorf("closers must run after the pre-stop delay: drained %v, closed %v", drained, closed)
+	}
+}
+
+func TestReload(t *testing.T) {
+	var failed atomic.Int32
+	ctx, group := Prepare(context.Background(), Notify{}, OnError{Func: func(context.Context, any, error) {
+		failed.Add(1)
+	}})
+
+	reloaded := make(chan string, 2)
+	Reload(group, "config", func(_ context.Context, name string) error {
+		reloaded <- name
+		return errors.New("invalid config")
+	})
+	Reload(group, "log", func(_ context.Context, name string) error {
+		reloaded <- name
+		return nil
+	})
+
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("signals are not supported:", err)
+	}
+
+	for _, name := range []string{"config", "log"} {
+		select {
+		case got := <-reloaded:
+			if got != name {
+				t.Errorf("expected %s, got %s", name, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("reload is not run")
+		}
+	}
+
+	if ctx.Err() != nil || failed.Load() != 1 {
+		t.Errorf("reload must not shutdown and errors must be reported: %v, %d", ctx.Err(), failed.Load())
+	}
+
+	group.cancel()
+	group.Wait(time.Second)
+}
+
+func TestListenInherited(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("listeners can't be inherited")
+	}
+
+	parent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parent.Close()
+
+	file, err := parent.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inherit()
+	key := "tcp://" + parent.Addr().String()
+	inheritMu.Lock()
+	inherited = append(inherited, &inheritedListener{key: key, file: file})
+	inheritMu.Unlock()
+
+	_, group := Prepare(context.Background(), Notify{})
+	listener, err := Listen(group, "tcp", parent.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if listener.Addr().String() != parent.Addr().String() || len(group.listeners) != 1 {
+		t.Errorf("listener must be inherited: %s", listener.Addr())
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("listeners can't be inherited")
+	}
+
+	t.Setenv(envUpgradeChild, "serve")
+	ctx, group := Prepare(context.Background(), Notify{}, Upgrade{ReadyTimeout: 10 * time.Second}, OnError{Func: func(_ context.Context, _ any, err error) {
+		t.Errorf("unexpected error %v", err)
+	}})
+
+	listener, err := Listen(group, "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(group.upgrade.Signal); err != nil {
+		t.Skip("signals are not supported:", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("group must be shut down when the child is ready")
+	}
+
+	// the parent stops accepting, connections go to the child
+	listener.Close()
+	conn, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	buf := make([]byte, 5)
+	if _, err := conn.Read(buf); err != nil || string(buf) != "child" {
+		t.Errorf("listener must be served by the child: %q %v", buf, err)
+	}
+
+	group.Wait(time.Second)
+}
+
+func TestUpgradeFailed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("listeners can't be inherited")
+	}
+
+	_, group := Prepare(context.Background(), Notify{})
+	defer group.Wait(time.Second)
+	defer group.cancel()
+
+	group.upgrade = Upgrade{ReadyTimeout: 200 * time.Millisecond}
+
+	for mode, expected := range map[string]string{
+		"fail": "isn't ready: EOF",
+		"hang": "isn't ready in 200ms",
+	} {
+		t.Setenv(envUpgradeChild, mode)
+
+		err := group.upgradeBinary(context.Background())
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: unexpected error %v", mode, err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	_, group := Prepare(context.Background(), Notify{}, OnError{Func: func(context.Context, any, error) {}})
+
+	var events []string
+	open := func(_ context.Context, name string) error {
+		events = append(events, "open "+name)
+		if name == "cache" {
+			return errors.New("unavailable")
+		}
+		return nil
+	}
+	closeTask := func(_ context.Context, name string) error {
+		events = append(events, "close "+name)
+		return nil
+	}
+
+	Open(group, "cache", open, closeTask, Name{Value: "cache"}, DependsOn{Names: []string{"db", "queue"}},
+		Attempts{Count: 2, Timeout: time.Second})
+	Open(group, "queue", open, closeTask, Name{Value: "queue"}, DependsOn{Names: []string{"db"}})
+	Open(group, "db", open, closeTask, Name{Value: "db"})
+
+	group.Ready()
+	err := group.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "open cache") {
+		t.Fatalf("expected open error, got %v", err)
+	}
+
+	expected := "open db,open queue,open cache,open cache,close queue,close db"
+	if strings.Join(events, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(events, ","))
+	}
+	if group.IsReady() || len(group.closer) != 0 {
+		t.Error("failed group must not be ready and must not close resources again")
+	}
+}
+
+func TestSDNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets")
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", "20000")
+
+	var healthy atomic.Bool
+	_, group := Prepare(context.Background(), Notify{}, Health{Check: func(context.Context) error {
+		if !healthy.Load() {
+			return errors.New("db is down")
+		}
+		return nil
+	}})
+
+	read := func(timeout time.Duration) string {
+		buf := make([]byte, 256)
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		n, _ := conn.Read(buf)
+		return string(buf[:n])
+	}
+
+	if state := read(50 * time.Millisecond); state != "" {
+		t.Fatalf("watchdog must be skipped while unhealthy, got %q", state)
+	}
+
+	group.Ready()
+	if state := read(time.Second); state != "READY=1\nSTATUS=ready" {
+		t.Fatalf("expected ready, got %q", state)
+	}
+
+	healthy.Store(true)
+	if state := read(time.Second); state != "WATCHDOG=1" {
+		t.Fatalf("expected watchdog, got %q", state)
+	}
+
+	group.cancel()
+	group.Wait(time.Second)
+
+	for state := read(time.Second); state != "STOPPING=1"; state = read(time.Second) {
+		if state != "WATCHDOG=1" {
+			t.Fatalf("expected stopping, got %q", state)
+		}
+	}
+}
+
+func TestLeakCheck(t *testing.T) {
+	var leakErr error
+	_, group := Prepare(context.Background(), Notify{}, LeakCheck{Timeout: 20 * time.Millisecond},
+		OnError{Func: func(_ context.Context, _ any, err error) { leakErr = err }})
+
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	Process(group, "handler", func(_ context.Context, _ string) error {
+		go func() { <-stuck }()
+		return nil
+	})
+
+	err := group.Wait(time.Second)
+
+	var leaks *LeakError
+	if !errors.As(err, &leaks) || leakErr != leaks || len(leaks.Leaks) != 1 || leaks.Leaks[0].State != "chan receive" ||
+		!strings.Contains(leaks.Leaks[0].CreatedBy[0].Function, "TestLeakCheck") {
+		t.Fatalf("expected leak of the handler goroutine: %v", err)
+	}
+}
+
+func TestOpenOptions(t *testing.T) {
+	_, group := Prepare(context.Background(), Notify{}, OnError{Func: func(context.Context, any, error) {}})
+
+	// spare capacity of the caller's slice must not be written
+	options := make([]any, 1, 2)
+	options[0] = Phase(PhaseFlush)
+	spare := options[:2]
+
+	Open(group, "db", stop, stop, options...)
+	if spare[1] != nil || len(group.closer) != 0 {
+		t.Fatalf("unexpected options %v or closers %d", spare, len(group.closer))
+	}
+
+	if err := group.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(group.closer) != 1 || group.closer[0].name != "string" || group.closer[0].phase != PhaseFlush {
+		t.Fatalf("unexpected closer %+v", group.closer)
+	}
+}
+
+func TestCheckHealthTimeout(t *testing.T) {
+	// the check ignores context, it's released after the test
+	hung := make(chan struct{})
+	t.Cleanup(func() { close(hung) })
+
+	_, group := Prepare(context.Background(), Notify{}, Health{Check: func(context.Context) error {
+		<-hung
+		return nil
+	}})
+	defer group.cancel()
+
+	if err := group.checkHealth(context.Background(), 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("hung check must fail by timeout, got %v", err)
+	}
+}
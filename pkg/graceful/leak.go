@@ -0,0 +1,243 @@
+package graceful
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"goplate/http/server/interceptor"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// LeakCheck option for Prepare, goroutines started after Prepare and alive after Wait are reported as *LeakError.
+	// Goroutines of runtime, os/signal and testing are ignored
+	LeakCheck struct {
+		// Optional. Default value DefaultLeakTimeout
+		Timeout time.Duration
+		// prefixes of functions in the stack of ignored goroutines, e.g. "github.com/valyala/fasthttp.(*workerPool)"
+		Ignore []string
+	}
+
+	// Leak is a group of goroutines with the same stack
+	Leak struct {
+		Count     int               `json:"count"`
+		State     string            `json:"state"`
+		Stack     interceptor.Stack `json:"stack"`
+		CreatedBy interceptor.Stack `json:"createdBy"`
+	}
+
+	LeakError struct {
+		Leaks []Leak
+	}
+
+	// Snapshot is ids of goroutines at the moment of TakeSnapshot
+	Snapshot map[uint64]struct{}
+
+	goroutine struct {
+		id        uint64
+		state     string
+		stack     interceptor.Stack
+		createdBy interceptor.Stack
+		entry     string
+	}
+)
+
+// DefaultLeakTimeout is time for goroutines to finish before they are reported
+const DefaultLeakTimeout = time.Second
+
+// entry functions of goroutines which belong to runtime and test framework
+var ignoredEntries = []string{"runtime.", "os/signal.", "testing."}
+
+func (le *LeakError) Error() string {
+	count := 0
+	for _, leak := range le.Leaks {
+		count += leak.Count
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d goroutines leaked", count)
+	for _, leak := range le.Leaks {
+		fmt.Fprintf(&b, "\n%d x [%s]\n%screated by %s", leak.Count, leak.State, leak.Stack.Error(), leak.CreatedBy.Error())
+	}
+	return b.String()
+}
+
+func (lc LeakCheck) withDefaults() LeakCheck {
+	if lc.Timeout <= 0 {
+		lc.Timeout = DefaultLeakTimeout
+	}
+	return lc
+}
+
+// TakeSnapshot remembers goroutines which are running now
+func TakeSnapshot() Snapshot {
+	snapshot := make(Snapshot)
+	for _, g := range goroutines() {
+		snapshot[g.id] = struct{}{}
+	}
+	return snapshot
+}
+
+// Leaks returns goroutines started after the snapshot and grouped by stack, it waits until timeout for them to finish
+func (s Snapshot) Leaks(timeout time.Duration, ignore ...string) []Leak {
+	deadline := time.Now().Add(timeout)
+
+	for wait := time.Millisecond; ; wait *= 2 {
+		var leaked []goroutine
+		for _, g := range goroutines() {
+			if _, ok := s[g.id]; !ok && !g.ignored(ignore) {
+				leaked = append(leaked, g)
+			}
+		}
+
+		if len(leaked) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return groupLeaks(leaked)
+		}
+
+		time.Sleep(min(wait, time.Until(deadline), 100*time.Millisecond))
+	}
+}
+
+func (g goroutine) ignored(ignore []string) bool {
+	for _, prefix := range ignoredEntries {
+		if strings.HasPrefix(g.entry, prefix) {
+			return true
+		}
+	}
+
+	for _, frame := range append(g.stack, g.createdBy...) {
+		for _, prefix := range ignore {
+			if strings.HasPrefix(frame.Function, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func groupLeaks(leaked []goroutine) []Leak {
+	var (
+		leaks []Leak
+		index = make(map[string]int)
+	)
+
+	for _, g := range leaked {
+		key := g.state + g.stack.Error() + g.createdBy.Error()
+
+		if i, ok := index[key]; ok {
+			leaks[i].Count++
+			continue
+		}
+
+		index[key] = len(leaks)
+		leaks = append(leaks, Leak{
+			Count:     1,
+			State:     g.state,
+			Stack:     g.stack,
+			CreatedBy: g.createdBy,
+		})
+	}
+
+	return leaks
+}
+
+// goroutines parses the dump of all goroutines except the current one
+func goroutines() []goroutine {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	blocks := bytes.Split(buf, []byte("\n\n"))
+
+	result := make([]goroutine, 0, len(blocks))
+	// the first block is the current goroutine
+	for _, block := range blocks[1:] {
+		if g, ok := parseGoroutine(string(block)); ok {
+			result = append(result, g)
+		}
+	}
+	return result
+}
+
+// parseGoroutine parses the block of runtime.Stack:
+//
+//	goroutine 18 [chan receive, 2 minutes]:
+//	main.worker(...)
+//		/app/main.go:12 +0x25
+//	created by main.main in goroutine 1
+//		/app/main.go:30 +0x3d
+func parseGoroutine(block string) (g goroutine, ok bool) {
+	lines := strings.Split(strings.TrimSpace(block), "\n")
+
+	header, found := strings.CutPrefix(lines[0], "goroutine ")
+	if !found {
+		return g, false
+	}
+
+	id, state, _ := strings.Cut(header, " [")
+	g.id, _ = strconv.ParseUint(id, 10, 64)
+	// wait duration changes between dumps
+	g.state, _, _ = strings.Cut(strings.TrimSuffix(state, "]:"), ",")
+
+	var frames interceptor.Stack
+	for i := 1; i+1 < len(lines); i += 2 {
+		function, location := lines[i], strings.TrimSpace(lines[i+1])
+
+		if strings.HasPrefix(function, "...") {
+			// "...additional frames elided..." has no location
+			i--
+			continue
+		}
+
+		if created, found := strings.CutPrefix(function, "created by "); found {
+			created, _, _ = strings.Cut(created, " in goroutine ")
+			g.createdBy = interceptor.Stack{stackFields(created, location)}
+			break
+		}
+
+		if j := strings.LastIndexByte(function, '('); j > 0 {
+			function = function[:j]
+		}
+		frames = append(frames, stackFields(function, location))
+		g.entry = function
+	}
+
+	// frames of the standard library are skipped as in interceptor.GetStacktrace, unless there are only them
+	for _, frame := range frames {
+		if !strings.HasPrefix(frame.Path, build.Default.GOROOT) {
+			g.stack = append(g.stack, frame)
+		}
+	}
+	if len(g.stack) == 0 {
+		g.stack = frames
+	}
+
+	return g, true
+}
+
+func stackFields(function, location string) interceptor.StackFields {
+	// "/app/main.go:12 +0x25"
+	location, _, _ = strings.Cut(location, " ")
+	path, line, _ := strings.Cut(location, ":")
+	if i := strings.LastIndexByte(location, ':'); i > 0 {
+		path, line = location[:i], location[i+1:]
+	}
+
+	return interceptor.StackFields{
+		Function: function,
+		Path:     path,
+		Line:     line,
+	}
+}
@@ -0,0 +1,38 @@
+// The `leaktest` package checks tests for goroutine leaks with the same rules as graceful.LeakCheck
+package leaktest
+
+import (
+	"goplate/pkg/graceful"
+	"testing"
+)
+
+// Check snapshots goroutines, the returned func fails the test when goroutines started after are still running.
+// Options: graceful.LeakCheck
+//
+//	defer leaktest.Check(t)()
+func Check(t testing.TB, options ...any) func() {
+	t.Helper()
+
+	check := graceful.LeakCheck{}
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case graceful.LeakCheck:
+			check = typed
+		}
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = graceful.DefaultLeakTimeout
+	}
+
+	snapshot := graceful.TakeSnapshot()
+
+	return func() {
+		t.Helper()
+
+		if leaks := snapshot.Leaks(check.Timeout, check.Ignore...); leaks != nil {
+			t.Error((&graceful.LeakError{Leaks: leaks}).Error())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package leaktest
+
+import (
+	"goplate/pkg/graceful"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Error(args ...any) {
+	r.errors = append(r.errors, args[0].(string))
+}
+
+func leak(stop chan struct{}) {
+	<-stop
+}
+
+func TestCheck(t *testing.T) {
+	r := &recorder{TB: t}
+	stop := make(chan struct{})
+	defer close(stop)
+
+	verify := Check(r, graceful.LeakCheck{Timeout: 20 * time.Millisecond})
+	for i := 0; i < 3; i++ {
+		go leak(stop)
+	}
+	go func() {}()
+	verify()
+
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "3 goroutines leaked") ||
+		!strings.Contains(r.errors[0], "leaktest.leak") || !strings.Contains(r.errors[0], "created by") {
+		t.Fatalf("expected 3 grouped leaks: %v", r.errors)
+	}
+
+	r.errors = nil
+	verify = Check(r, graceful.LeakCheck{Timeout: 20 * time.Millisecond, Ignore: []string{"goplate/pkg/graceful/leaktest.leak"}})
+	go leak(stop)
+	verify()
+	if len(r.errors) != 0 {
+		t.Errorf("ignored goroutines must not be reported: %v", r.errors)
+	}
+}
@@ -0,0 +1,204 @@
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type (
+	// Attempts option for Open, Count of attempts (default 1), Timeout of an attempt and Delay between attempts
+	Attempts struct {
+		Count   int
+		Timeout time.Duration
+		Delay   time.Duration
+	}
+
+	// DependsOn option for Open, names of resources which are opened before
+	DependsOn struct {
+		Names []string
+	}
+
+	opener struct {
+		name      string
+		dependsOn []string
+		attempts  Attempts
+		resource  any
+		open      any
+		task      task
+		// registered after successful start of the group
+		closer *closer
+	}
+)
+
+// Open registers a startup task of the resource, it runs by Start, taskClose may be nil.
+// Options: Name (default is the type of resource), DependsOn, Attempts and options of Close for taskClose
+func Open[T_resource any](group *CloseGroup, resource T_resource, taskOpen, taskClose Task[T_resource], options ...any) {
+	o := opener{
+		name:     fmt.Sprintf("%T", resource),
+		attempts: Attempts{Count: 1},
+		resource: resource,
+		open:     taskOpen,
+		task: func(ctx context.Context, data, open any, errors chan error) {
+			errors <- open.(Task[T_resource])(ctx, data.(T_resource))
+		},
+	}
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Name:
+			o.name = typed.Value
+		case DependsOn:
+			o.dependsOn = typed.Names
+		case Attempts:
+			o.attempts = typed
+			o.attempts.Count = max(typed.Count, 1)
+		}
+	}
+
+	if taskClose != nil {
+		// the name of the closer is the same, Name option is shared
+		c := newCloser(resource, taskClose, options...)
+		o.closer = &c
+	}
+
+	group.openMu.Lock()
+	group.openers = append(group.openers, o)
+	group.openMu.Unlock()
+}
+
+// Start runs Open tasks in dependency order, when a task fails the opened resources are closed in reverse order.
+// Closers of resources are registered only when all tasks succeed, then the group may become ready
+func (group *CloseGroup) Start(ctx context.Context) error {
+	group.openMu.Lock()
+	openers, err := sortOpeners(group.openers)
+	group.openMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	log := handlerLogger(group.onError.Handler)
+
+	for i, o := range openers {
+		start := time.Now()
+		group.status(ctx, "opening "+o.name)
+
+		if err := group.open(ctx, o); err != nil {
+			group.closeOpened(context.WithoutCancel(ctx), openers[:i])
+			return fmt.Errorf("open %s: %w", o.name, err)
+		}
+
+		log.InfoContext(ctx, "Opened", "name", o.name, "duration", time.Since(start).String())
+	}
+
+	for _, o := range openers {
+		if o.closer != nil {
+			group.closer = append(group.closer, *o.closer)
+		}
+	}
+
+	group.opened.Store(true)
+	group.checkReady()
+
+	return nil
+}
+
+func (group *CloseGroup) open(ctx context.Context, o opener) (err error) {
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if o.attempts.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, o.attempts.Timeout)
+		}
+
+		errors := make(chan error, 1)
+		o.task(attemptCtx, o.resource, o.open, errors)
+		err = <-errors
+		cancel()
+
+		if err == nil || attempt >= o.attempts.Count || ctx.Err() != nil {
+			return err
+		}
+
+		handlerLogger(group.onError.Handler).WarnContext(ctx, "Open retry",
+			"name", o.name, "attempt", attempt, "error", err.Error())
+
+		timer := time.NewTimer(o.attempts.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// closeOpened closes resources in reverse order of opening
+func (group *CloseGroup) closeOpened(ctx context.Context, opened []opener) {
+	for i := len(opened) - 1; i >= 0; i-- {
+		c := opened[i].closer
+		if c == nil {
+			continue
+		}
+
+		done := make(chan CloseResult, 1)
+		group.runCloser(ctx, *c, done)
+
+		if result := <-done; result.Status != CloseFinished {
+			group.onError.Func(ctx, group.onError.Handler, &CloseError{CloseResult: result})
+		}
+	}
+}
+
+// sortOpeners orders openers by dependencies, the order of registration is kept otherwise
+func sortOpeners(openers []opener) ([]opener, error) {
+	index := make(map[string]int, len(openers))
+	for i, o := range openers {
+		index[o.name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		state  = make([]int, len(openers))
+		sorted = make([]opener, 0, len(openers))
+		visit  func(i int) error
+	)
+
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("open %s: dependency cycle", openers[i].name)
+		case visited:
+			return nil
+		}
+
+		state[i] = visiting
+		for _, name := range openers[i].dependsOn {
+			j, ok := index[name]
+			if !ok {
+				return fmt.Errorf("open %s: unknown dependency %s", openers[i].name, name)
+			}
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+
+		sorted = append(sorted, openers[i])
+		return nil
+	}
+
+	for i := range openers {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,85 @@
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadNotify option for Prepare, signals which run Reload tasks
+type ReloadNotify struct {
+	Signals []os.Signal
+}
+
+var DefaultReloadNotify = ReloadNotify{
+	Signals: []os.Signal{syscall.SIGHUP},
+}
+
+// Reload registers a task which runs on reload signal without shutdown, e.g. re-read config or reopen log files.
+// Errors are passed to OnError and the process keeps running. Options: Name (default is the type of resource)
+func Reload[T_resource any](group *CloseGroup, resource T_resource, taskReload Task[T_resource], options ...any) {
+	name := fmt.Sprintf("%T", resource)
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Name:
+			name = typed.Value
+		}
+	}
+
+	group.reloadMu.Lock()
+	group.reloaders = append(group.reloaders, closer{
+		name:     name,
+		resource: resource,
+		close:    taskReload,
+		task: func(ctx context.Context, data, reload any, errors chan error) {
+			errors <- reload.(Task[T_resource])(ctx, data.(T_resource))
+		},
+	})
+	group.reloadMu.Unlock()
+
+	// signals are caught only when there is something to reload, SIGHUP terminates by default
+	group.reloadOnce.Do(group.notifyReload)
+}
+
+// RunReload runs all Reload tasks one by one
+func (group *CloseGroup) RunReload(ctx context.Context) {
+	group.reloadMu.Lock()
+	reloaders := append([]closer(nil), group.reloaders...)
+	group.reloadMu.Unlock()
+
+	for _, reloader := range reloaders {
+		errors := make(chan error, 1)
+		reloader.task(ctx, reloader.resource, reloader.close, errors)
+
+		if err := <-errors; err != nil {
+			group.onError.Func(ctx, group.onError.Handler, fmt.Errorf("reload %s: %w", reloader.name, err))
+		}
+	}
+}
+
+func (group *CloseGroup) notifyReload() {
+	if len(group.reloadNotify.Signals) == 0 {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, group.reloadNotify.Signals...)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case sig := <-signals:
+				handlerLogger(group.onError.Handler).InfoContext(group.shutdownCtx, "Reload", "signal", sig.String())
+				group.RunReload(group.shutdownCtx)
+			case <-group.shutdownCtx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,117 @@
+package graceful
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+type (
+	RestartPolicy string
+
+	// Supervise option for Process, the task is restarted by the policy instead of shutdown of the group.
+	// When restarts exceed MaxRestarts within Window or the task fails without restart, the group is shut down
+	Supervise struct {
+		Policy RestartPolicy
+		// Optional. Default value 100ms, doubled by every restart within Window
+		Backoff time.Duration
+		// Optional. Default value 30s
+		MaxBackoff time.Duration
+		// Optional. Default value 5
+		MaxRestarts int
+		// Optional. Default value 1m
+		Window time.Duration
+	}
+)
+
+const (
+	// RestartAlways restarts the task after any return
+	RestartAlways RestartPolicy = "always"
+	// RestartOnFailure restarts the task after error, the task returned nil is finished
+	RestartOnFailure RestartPolicy = "on-failure"
+	// RestartNever doesn't restart, the task returned nil is finished without shutdown
+	RestartNever RestartPolicy = "never"
+)
+
+func (s Supervise) withDefaults() Supervise {
+	if s.Backoff <= 0 {
+		s.Backoff = 100 * time.Millisecond
+	}
+	if s.MaxBackoff <= 0 {
+		s.MaxBackoff = 30 * time.Second
+	}
+	if s.MaxRestarts <= 0 {
+		s.MaxRestarts = 5
+	}
+	if s.Window <= 0 {
+		s.Window = time.Minute
+	}
+	return s
+}
+
+// backoff is exponential by the number of restarts with jitter in [delay/2, delay]
+func (s Supervise) backoff(restarts int) time.Duration {
+	delay := s.Backoff
+	for i := 1; i < restarts && delay < s.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, s.MaxBackoff)
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func superviseProcess[T_resource any](group *CloseGroup, name string, resource T_resource, process Task[T_resource], supervise Supervise) {
+	defer group.processes.Done()
+
+	var (
+		ctx      = group.shutdownCtx
+		log      = handlerLogger(group.onError.Handler)
+		restarts []time.Time
+	)
+
+	for {
+		err := process(ctx, resource)
+
+		switch {
+		case ctx.Err() != nil:
+			// shutdown, nothing to restart, fail skips ctx.Err() of the task
+			group.fail(err)
+			return
+		case err == nil && supervise.Policy != RestartAlways:
+			log.InfoContext(ctx, "Process finished", "name", name)
+			return
+		case err != nil && supervise.Policy == RestartNever:
+			group.fail(fmt.Errorf("process %s: %w", name, err))
+			return
+		}
+
+		// keep restarts within the window only
+		now := time.Now()
+		for len(restarts) > 0 && now.Sub(restarts[0]) > supervise.Window {
+			restarts = restarts[1:]
+		}
+		if len(restarts) >= supervise.MaxRestarts {
+			group.fail(fmt.Errorf("process %s: %d restarts within %s: %w", name, len(restarts), supervise.Window, err))
+			return
+		}
+		restarts = append(restarts, now)
+
+		delay := supervise.backoff(len(restarts))
+		log.WarnContext(ctx, "Process restart", "name", name, "restart", len(restarts), "backoff", delay.String(), "error", errString(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,120 @@
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health option for Prepare, watchdog keepalives are skipped while Check fails,
+// a call is cancelled after the watchdog interval
+type Health struct {
+	Check func(ctx context.Context) error
+}
+
+const (
+	// systemd notify protocol, see sd_notify(3)
+	envNotifySocket = "NOTIFY_SOCKET"
+	envWatchdogUsec = "WATCHDOG_USEC"
+	envWatchdogPID  = "WATCHDOG_PID"
+)
+
+// SDNotify sends the state to systemd, e.g. "READY=1" or "STATUS=...", it's noop without NOTIFY_SOCKET
+func SDNotify(state string) error {
+	socket := os.Getenv(envNotifySocket)
+	if socket == "" {
+		return nil
+	}
+
+	// abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval is a half of WATCHDOG_USEC as recommended by systemd, zero when the watchdog is disabled
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(envWatchdogUsec), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+
+	if pid := os.Getenv(envWatchdogPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+func (group *CloseGroup) notify(ctx context.Context, state string) {
+	if err := SDNotify(state); err != nil {
+		group.onError.Func(ctx, group.onError.Handler, err)
+	}
+}
+
+// status reports the current phase to systemd
+func (group *CloseGroup) status(ctx context.Context, status string) {
+	group.notify(ctx, "STATUS="+status)
+}
+
+// watchdog sends keepalives until the group is closed, a failed health check skips the keepalive
+func (group *CloseGroup) watchdog(ctx context.Context) {
+	interval := watchdogInterval()
+	if interval <= 0 || os.Getenv(envNotifySocket) == "" {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := group.checkHealth(ctx, interval); err != nil {
+					handlerLogger(group.onError.Handler).WarnContext(ctx, "Watchdog is skipped", "error", err.Error())
+					continue
+				}
+
+				group.notify(ctx, "WATCHDOG=1")
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// checkHealth runs Health.Check bounded by timeout, a hung check fails instead of stalling keepalives
+func (group *CloseGroup) checkHealth(ctx context.Context, timeout time.Duration) error {
+	if group.health.Check == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- group.health.Check(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("health check: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,330 @@
+package graceful
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Upgrade option for Prepare, on Signal (default SIGUSR2, unix only) the binary is started again
+	// with listeners of the group, when the child is ready the group is shut down
+	Upgrade struct {
+		Signal os.Signal
+		// Optional. Default value 1m, the child is killed when it isn't ready in time
+		ReadyTimeout time.Duration
+	}
+
+	inheritedListener struct {
+		key  string
+		file *os.File
+		used bool
+	}
+
+	groupListener struct {
+		key      string
+		listener net.Listener
+	}
+
+	fileListener interface {
+		File() (*os.File, error)
+	}
+)
+
+const (
+	// EnvListeners has keys of listeners passed by the parent from fd 3, e.g. "tcp://:8080,tcp://:9090"
+	EnvListeners = "GRACEFUL_LISTENERS"
+	// EnvReadyFD has fd of the pipe to report readiness to the parent
+	EnvReadyFD = "GRACEFUL_READY_FD"
+
+	// systemd socket activation
+	envListenFDs  = "LISTEN_FDS"
+	envListenPID  = "LISTEN_PID"
+	envListenName = "LISTEN_FDNAMES"
+
+	listenFDsStart = 3
+)
+
+var (
+	inheritOnce sync.Once
+	inheritMu   sync.Mutex
+	inherited   []*inheritedListener
+	readyPipe   *os.File
+)
+
+func (u Upgrade) withDefaults() Upgrade {
+	if u.Signal == nil {
+		u.Signal = defaultUpgradeSignal
+	}
+	if u.ReadyTimeout <= 0 {
+		u.ReadyTimeout = time.Minute
+	}
+	return u
+}
+
+// inherit reads fds passed by the parent or systemd once per process, variables are unset to not leak to children
+func inherit() {
+	inheritOnce.Do(func() {
+		if fd, err := strconv.Atoi(os.Getenv(EnvReadyFD)); err == nil {
+			readyPipe = os.NewFile(uintptr(fd), "ready")
+		}
+
+		switch {
+		case os.Getenv(EnvListeners) != "":
+			for i, key := range strings.Split(os.Getenv(EnvListeners), ",") {
+				inherited = append(inherited, &inheritedListener{
+					key:  key,
+					file: os.NewFile(uintptr(listenFDsStart+i), key),
+				})
+			}
+		case os.Getenv(envListenFDs) != "":
+			count, _ := strconv.Atoi(os.Getenv(envListenFDs))
+			if pid := os.Getenv(envListenPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+				break
+			}
+
+			names := strings.Split(os.Getenv(envListenName), ":")
+			for i := 0; i < count; i++ {
+				// systemd listeners are matched by order
+				inherited = append(inherited, &inheritedListener{
+					file: os.NewFile(uintptr(listenFDsStart+i), at(names, i)),
+				})
+			}
+		}
+
+		for _, name := range []string{EnvListeners, EnvReadyFD, envListenFDs, envListenPID, envListenName} {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+func at(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
+
+// Listen returns the listener inherited from the parent process or systemd, otherwise a new one.
+// The listener is passed to the child on Upgrade
+func Listen(group *CloseGroup, network, address string) (net.Listener, error) {
+	inherit()
+
+	key := network + "://" + address
+
+	listener, err := takeInherited(key)
+	if err != nil {
+		return nil, err
+	}
+	if listener == nil {
+		listener, err = net.Listen(network, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	group.listenerMu.Lock()
+	group.listeners = append(group.listeners, groupListener{key: key, listener: listener})
+	group.listenerMu.Unlock()
+
+	return listener, nil
+}
+
+func takeInherited(key string) (net.Listener, error) {
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	for _, in := range inherited {
+		if in.used || (in.key != "" && in.key != key) {
+			continue
+		}
+		in.used = true
+
+		listener, err := net.FileListener(in.file)
+		// listener has own dup of fd
+		in.file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit %s: %w", key, err)
+		}
+		return listener, nil
+	}
+	return nil, nil
+}
+
+// Ready marks the application ready, e.g. when the server listens.
+// The group is ready when Open tasks are succeeded too, then the parent process is notified
+func (group *CloseGroup) Ready() {
+	group.readyCalled.Store(true)
+	group.checkReady()
+}
+
+// IsReady reports readiness of the group
+func (group *CloseGroup) IsReady() bool {
+	return group.ready.Load()
+}
+
+func (group *CloseGroup) checkReady() {
+	group.openMu.Lock()
+	opened := len(group.openers) == 0 || group.opened.Load()
+	group.openMu.Unlock()
+
+	if !opened || !group.readyCalled.Load() || !group.ready.CompareAndSwap(false, true) {
+		return
+	}
+
+	state := "READY=1\nSTATUS=ready"
+	if notifyParent() {
+		// the child of Upgrade becomes the main process of the service
+		state = "MAINPID=" + strconv.Itoa(os.Getpid()) + "\n" + state
+	}
+	group.notify(group.shutdownCtx, state)
+}
+
+// notifyParent reports readiness to the parent process when it's started by Upgrade
+func notifyParent() (notified bool) {
+	inherit()
+
+	inheritMu.Lock()
+	defer inheritMu.Unlock()
+
+	if readyPipe == nil {
+		return false
+	}
+
+	readyPipe.Write([]byte("ready"))
+	readyPipe.Close()
+	readyPipe = nil
+
+	return true
+}
+
+func (group *CloseGroup) notifyUpgrade() {
+	if group.upgrade.Signal == nil {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, group.upgrade.Signal)
+
+	go func() {
+		defer signal.Stop(signals)
+
+		for {
+			select {
+			case <-signals:
+				if err := group.upgradeBinary(group.shutdownCtx); err != nil {
+					group.onError.Func(group.shutdownCtx, group.onError.Handler, fmt.Errorf("upgrade: %w", err))
+					continue
+				}
+
+				// the child serves, drain and exit
+				group.cancel()
+				return
+			case <-group.shutdownCtx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// upgradeBinary starts the binary again with listeners of the group and waits until the child is ready
+func (group *CloseGroup) upgradeBinary(ctx context.Context) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	group.listenerMu.Lock()
+	listeners := append([]groupListener(nil), group.listeners...)
+	group.listenerMu.Unlock()
+
+	files := []*os.File{os.Stdin, os.Stdout, os.Stderr}
+	keys := make([]string, 0, len(listeners))
+
+	for _, gl := range listeners {
+		fl, ok := gl.listener.(fileListener)
+		if !ok {
+			return fmt.Errorf("listener %s can't be passed", gl.key)
+		}
+
+		file, err := fl.File()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		files = append(files, file)
+		keys = append(keys, gl.key)
+	}
+
+	ready, readyWrite, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+
+	env := []string{
+		EnvListeners + "=" + strings.Join(keys, ","),
+		EnvReadyFD + "=" + strconv.Itoa(len(files)),
+	}
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, EnvListeners+"=") && !strings.HasPrefix(kv, EnvReadyFD+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	child, err := os.StartProcess(executable, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: append(files, readyWrite),
+	})
+	// the child has own copy
+	readyWrite.Close()
+	if err != nil {
+		return err
+	}
+
+	log := handlerLogger(group.onError.Handler)
+	log.InfoContext(ctx, "Upgrade", "pid", child.Pid)
+
+	result := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 5)
+		_, err := io.ReadFull(ready, buf)
+		if err != nil {
+			// EOF when the child exited before readiness
+			err = fmt.Errorf("child %d isn't ready: %w", child.Pid, err)
+		}
+		result <- err
+	}()
+
+	timer := time.NewTimer(group.upgrade.ReadyTimeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-result:
+	case <-timer.C:
+		err = fmt.Errorf("child %d isn't ready in %s", child.Pid, group.upgrade.ReadyTimeout)
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		// wait releases the killed child, otherwise it stays a zombie
+		child.Kill()
+		child.Wait()
+		return err
+	}
+
+	log.InfoContext(ctx, "Upgrade is ready", "pid", child.Pid)
+	// the child is adopted by init after exit of the parent
+	child.Release()
+
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package graceful
+
+import "os"
+
+// upgrade isn't supported, fds can't be passed to the child
+var defaultUpgradeSignal os.Signal
@@ -0,0 +1,10 @@
+//go:build unix
+
+package graceful
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultUpgradeSignal os.Signal = syscall.SIGUSR2
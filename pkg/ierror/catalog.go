@@ -0,0 +1,190 @@
+package ierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// CatalogEntry describes an error code, translations are keyed by language tag (ru, en-US)
+	CatalogEntry struct {
+		Code         string            `json:"code" yaml:"code"`
+		Status       int               `json:"status" yaml:"status"`
+		Message      string            `json:"message" yaml:"message"`
+		Translations map[string]string `json:"translations,omitempty" yaml:"translations,omitempty"`
+	}
+
+	// Catalog maps error codes to status and messages
+	Catalog struct {
+		entries map[string]CatalogEntry
+	}
+)
+
+var catalog *Catalog
+
+// UseCatalog sets the catalog used while rendering Error, initialize only once, in main.go
+func UseCatalog(c *Catalog) {
+	catalog = c
+}
+
+// LoadCatalog reads .json, .yaml and .yml files matched by patterns (fs.Glob), every file is a map of code to entry:
+//
+//	E_MODEL:
+//	  status: 400
+//	  message: see documentation
+//	  translations:
+//	    ru: смотрите документацию
+func LoadCatalog(fsys fs.FS, patterns ...string) (*Catalog, error) {
+	c := &Catalog{
+		entries: make(map[string]CatalogEntry),
+	}
+
+	for _, pattern := range patterns {
+		names, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range names {
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+
+			entries := make(map[string]CatalogEntry)
+
+			switch strings.ToLower(path.Ext(name)) {
+			case ".json":
+				err = json.Unmarshal(data, &entries)
+			case ".yaml", ".yml":
+				err = yaml.Unmarshal(data, &entries)
+			default:
+				err = fmt.Errorf("unknown catalog format")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			for code, entry := range entries {
+				if _, ok := c.entries[code]; ok {
+					return nil, fmt.Errorf("%s: duplicate code %s", name, code)
+				}
+
+				entry.Code = code
+				if len(entry.Translations) > 0 {
+					translations := make(map[string]string, len(entry.Translations))
+					for lang, msg := range entry.Translations {
+						translations[strings.ToLower(lang)] = msg
+					}
+					entry.Translations = translations
+				}
+				c.entries[code] = entry
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// Lookup returns the entry of the code
+func (c *Catalog) Lookup(code string) (entry CatalogEntry, ok bool) {
+	if c == nil {
+		return entry, false
+	}
+	entry, ok = c.entries[code]
+	return
+}
+
+// Codes returns all entries sorted by code
+func (c *Catalog) Codes() []CatalogEntry {
+	result := make([]CatalogEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+	return result
+}
+
+// Localize returns the message for the best language of Accept-Language header value by q-values,
+// "ru-RU" matches "ru" and vice versa. The default message if there is no translation
+func (ce CatalogEntry) Localize(acceptLanguage string) string {
+	if len(ce.Translations) == 0 {
+		return ce.Message
+	}
+
+	desired, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(desired) == 0 {
+		return ce.Message
+	}
+
+	langs := make([]string, 0, len(ce.Translations))
+	for lang := range ce.Translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	// the first tag is the default message
+	supported := []language.Tag{language.Und}
+	keys := []string{""}
+	for _, lang := range langs {
+		if tag, err := language.Parse(lang); err == nil {
+			supported = append(supported, tag)
+			keys = append(keys, lang)
+		}
+	}
+
+	_, index, confidence := language.NewMatcher(supported).Match(desired...)
+	if index == 0 || confidence == language.No {
+		return ce.Message
+	}
+	return ce.Translations[keys[index]]
+}
+
+// WriteMarkdown writes the table of all codes for the API documentation
+func (c *Catalog) WriteMarkdown(w io.Writer) error {
+	var languages []string
+	seen := make(map[string]bool)
+	codes := c.Codes()
+
+	for _, entry := range codes {
+		for lang := range entry.Translations {
+			if !seen[lang] {
+				seen[lang] = true
+				languages = append(languages, lang)
+			}
+		}
+	}
+	sort.Strings(languages)
+
+	header := []string{"Code", "Status", "Message"}
+	header = append(header, languages...)
+
+	lines := []string{
+		"| " + strings.Join(header, " | ") + " |",
+		"|" + strings.Repeat(" --- |", len(header)),
+	}
+	for _, entry := range codes {
+		row := []string{entry.Code, strconv.Itoa(entry.Status), entry.Message}
+		for _, lang := range languages {
+			row = append(row, entry.Translations[lang])
+		}
+		for i := range row {
+			row[i] = strings.ReplaceAll(row[i], "|", "\\|")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
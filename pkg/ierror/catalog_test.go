@@ -0,0 +1,61 @@
+package ierror
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCatalog(t *testing.T) {
+	fsys := fstest.MapFS{
+		"errors/common.yaml": {Data: []byte("E_MODEL:\n  status: 400\n  message: see documentation\n  translations:\n    RU: смотрите документацию\n")},
+		"errors/auth.json":   {Data: []byte(`{"E_AUTH": {"status": 401, "message": "unauthorized", "translations": {"kk-KZ": "рұқсат жоқ"}}}`)},
+	}
+
+	c, err := LoadCatalog(fsys, "errors/*.yaml", "errors/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, ok := c.Lookup("E_MODEL")
+	if !ok || entry.Status != http.StatusBadRequest {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	cases := map[string]string{
+		"":                        "see documentation",
+		"en-US,en;q=0.9":          "see documentation",
+		"ru-RU,ru;q=0.9,en;q=0.8": "смотрите документацию",
+		"en;q=0.5,ru;q=0.9":       "смотрите документацию",
+		"ru;q=0,en":               "see documentation",
+		"de, ru-RU;q=0.5":         "смотрите документацию",
+	}
+	for header, expected := range cases {
+		if msg := entry.Localize(header); msg != expected {
+			t.Errorf("%q: expected %q, got %q", header, expected, msg)
+		}
+	}
+
+	UseCatalog(c)
+	defer UseCatalog(nil)
+
+	for _, header := range []string{"kk-KZ", "kk", "en;q=0.1, kk-kz"} {
+		rendered := NewError("E_AUTH", 0, "").Render(header)
+		if rendered.StatusCode != http.StatusUnauthorized || *rendered.Msg != "рұқсат жоқ" {
+			t.Errorf("%q: unexpected render: %+v", header, rendered)
+		}
+	}
+
+	var doc strings.Builder
+	if err = c.WriteMarkdown(&doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(doc.String(), "| E_AUTH | 401 | unauthorized | рұқсат жоқ |  |") {
+		t.Errorf("unexpected markdown:\n%s", doc.String())
+	}
+
+	if _, err = LoadCatalog(fstest.MapFS{"a.json": fsys["errors/auth.json"], "b.json": fsys["errors/auth.json"]}, "*.json"); err == nil {
+		t.Error("expected duplicate code error")
+	}
+}
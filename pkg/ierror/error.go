@@ -0,0 +1,146 @@
+package ierror
+
+import (
+	"errors"
+	"fmt"
+	"goplate/http/reqresp"
+	"goplate/http/server/interceptor"
+	"net/http"
+)
+
+type (
+	// Error is an application error with a machine code, http status and user message,
+	// the stack is captured at creation
+	Error struct {
+		Code    string
+		Status  int
+		Message string
+		Cause   error
+		Values  map[string]any
+		Stack   interceptor.Stack
+		// CategoryUnknown is derived from Status: 4xx expected, 502-504 transient
+		Category Category
+	}
+)
+
+// NewError creates an error without cause, status 0 is rendered as 500
+func NewError(code string, status int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+		Stack:   interceptor.GetStacktrace(),
+	}
+}
+
+// Wrap creates an error with cause
+func Wrap(cause error, code string, status int, message string) *Error {
+	return &Error{
+		Code:    code,
+		Status:  status,
+		Message: message,
+		Cause:   cause,
+		Stack:   interceptor.GetStacktrace(),
+	}
+}
+
+// With adds key/value to the error
+func (e *Error) With(key string, value any) *Error {
+	if e.Values == nil {
+		e.Values = make(map[string]any)
+	}
+	e.Values[key] = value
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	if e.Cause != nil {
+		msg += ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports errors with the same code as equal, so a declared error may be used as a sentinel
+func (e *Error) Is(target error) bool {
+	typed, ok := target.(*Error)
+	return ok && typed.Code != "" && typed.Code == e.Code
+}
+
+func (e *Error) ErrorCategory() Category {
+	switch {
+	case e.Category != CategoryUnknown:
+		return e.Category
+	case e.Status >= http.StatusBadRequest && e.Status < http.StatusInternalServerError:
+		return CategoryExpected
+	case e.Status >= http.StatusBadGateway && e.Status <= http.StatusGatewayTimeout:
+		return CategoryTransient
+	}
+	return CategoryUnknown
+}
+
+// Render implements reqresp.Renderer, empty status and message are taken from the catalog (UseCatalog)
+// with the language of Accept-Language
+func (e *Error) Render(acceptLanguage string) *reqresp.Error {
+	status, message := e.Status, e.Message
+
+	if entry, ok := catalog.Lookup(e.Code); ok {
+		if status == 0 {
+			status = entry.Status
+		}
+		if message == "" {
+			message = entry.Localize(acceptLanguage)
+		}
+	}
+
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	var code *string
+	if e.Code != "" {
+		code = &e.Code
+	}
+
+	return reqresp.NewError(status, e, message, code)
+}
+
+// AsCode finds the first error with the code in the chain of err
+func AsCode(err error, code string) (*Error, bool) {
+	for err != nil {
+		var typed *Error
+		if !errors.As(err, &typed) {
+			return nil, false
+		}
+		if typed.Code == code {
+			return typed, true
+		}
+		err = typed.Cause
+	}
+	return nil, false
+}
+
+// Code returns the code of the first Error in the chain of err
+func Code(err error) string {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Code
+	}
+	return ""
+}
+
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		fmt.Fprintf(s, "%s\n%s", e.Error(), e.Stack.Error())
+	default:
+		fmt.Fprint(s, e.Error())
+	}
+}
@@ -0,0 +1,42 @@
+package ierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+var errModel = NewError("E_MODEL", http.StatusBadRequest, "see documentation")
+
+func TestError(t *testing.T) {
+	cause := errors.New("bad request")
+	err := fmt.Errorf("handler: %w", Wrap(cause, "E_MODEL", http.StatusBadRequest, "invalid model").With("field", "phone"))
+
+	if !errors.Is(err, errModel) {
+		t.Error("errors.Is by code failed")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("cause is not unwrapped")
+	}
+	if errors.Is(err, NewError("E_OTHER", 0, "")) {
+		t.Error("different codes must not match")
+	}
+
+	typed, ok := AsCode(err, "E_MODEL")
+	if !ok || typed.Values["field"] != "phone" {
+		t.Fatalf("AsCode failed: %+v", typed)
+	}
+	if len(typed.Stack) == 0 {
+		t.Error("stack is not captured")
+	}
+
+	rendered := typed.Render("")
+	if rendered.StatusCode != http.StatusBadRequest || *rendered.Msg != "invalid model" || *rendered.MsgType != "E_MODEL" {
+		t.Errorf("unexpected render: %+v", rendered)
+	}
+
+	if NewError("E_INTERNAL", 0, "").Render("").StatusCode != http.StatusInternalServerError {
+		t.Error("status 0 must be rendered as 500")
+	}
+}
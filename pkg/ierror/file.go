@@ -0,0 +1,61 @@
+package ierror
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+type (
+	// FileReporter appends reports to a local JSON Lines file
+	FileReporter struct {
+		mu   sync.Mutex
+		path string
+		file *os.File
+		enc  *json.Encoder
+	}
+)
+
+func NewFileReporter(path string) (*FileReporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileReporter{
+		path: path,
+		file: file,
+		enc:  json.NewEncoder(file),
+	}, nil
+}
+
+func (fr *FileReporter) Report(_ context.Context, report Report) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return fr.enc.Encode(report)
+}
+
+// Reopen opens the file by the path again, e.g. after rotation by logrotate
+func (fr *FileReporter) Reopen(_ context.Context) error {
+	file, err := os.OpenFile(fr.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	old := fr.file
+	fr.file, fr.enc = file, json.NewEncoder(file)
+
+	return old.Close()
+}
+
+func (fr *FileReporter) Close(_ context.Context) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	return fr.file.Close()
+}
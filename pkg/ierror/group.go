@@ -0,0 +1,159 @@
+package ierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goplate/http/server/interceptor"
+	"goplate/pkg/trace_logger"
+	"log/slog"
+	"sync"
+)
+
+type (
+	// Group is errgroup which recovers panics, the first error or panic cancels the context of the group
+	Group struct {
+		a      *Asserter
+		ctx    context.Context //nolint:containedctx
+		cancel context.CancelCauseFunc
+
+		wg  sync.WaitGroup
+		sem chan struct{}
+
+		once sync.Once
+		err  error
+	}
+)
+
+// Go runs fn in a goroutine of the default asserter
+func Go(ctx context.Context, fn func(ctx context.Context) error) {
+	Default().Go(ctx, fn)
+}
+
+// NewGroup creates a group of the default asserter
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	return Default().NewGroup(ctx)
+}
+
+// Go runs fn in a goroutine with ctx (trace_id and cancellation are inherited),
+// the returned error is asserted, a panic is recovered, logged and reported
+func (a *Asserter) Go(ctx context.Context, fn func(ctx context.Context) error) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				a.recovered(ctx, r, interceptor.GetStacktrace())
+			}
+		}()
+
+		a.assert(ctx, fn(ctx), "goroutine error", nil)
+	}()
+}
+
+// NewGroup creates a group with context derived from ctx, it's cancelled by the first error or Wait
+func (a *Asserter) NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	return &Group{
+		a:      a,
+		ctx:    ctx,
+		cancel: cancel,
+	}, ctx
+}
+
+// SetLimit limits the number of active goroutines, n <= 0 is no limit.
+// Must not be called while goroutines are active
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs fn when the limit allows, fn is skipped when the group is already cancelled
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	if g.ctx.Err() != nil {
+		g.fail(context.Cause(g.ctx))
+		return
+	}
+
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			g.fail(context.Cause(g.ctx))
+			return
+		}
+
+		// both cases may be ready, select picks randomly
+		if g.ctx.Err() != nil {
+			<-g.sem
+			g.fail(context.Cause(g.ctx))
+			return
+		}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if g.sem != nil {
+				<-g.sem
+			}
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				g.fail(g.a.recovered(g.ctx, r, interceptor.GetStacktrace()))
+			}
+		}()
+
+		if err := fn(g.ctx); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+// Wait waits for all goroutines and returns the first error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
+
+func (g *Group) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		g.cancel(err)
+	})
+}
+
+// recovered logs and reports the panic with the stack of the panic
+func (a *Asserter) recovered(ctx context.Context, r any, stack interceptor.Stack) *Error {
+	cause, ok := r.(error)
+	if !ok {
+		cause = errors.New(fmt.Sprint(r))
+	}
+
+	err := &Error{
+		Code:     "E_PANIC",
+		Message:  "panic",
+		Cause:    cause,
+		Stack:    stack,
+		Category: CategoryBug,
+	}
+
+	if a.log != nil {
+		trace_logger.L(ctx, a.log).LogAttrs(ctx, slog.LevelError, "goroutine panic",
+			slog.String("error", cause.Error()),
+			slog.Any("stacktrace", stack.Print()),
+		)
+	}
+
+	if a.reporter != nil {
+		report := NewReport(ctx, err, stack)
+		report.Panic = true
+		a.send(ctx, report)
+	}
+
+	return err
+}
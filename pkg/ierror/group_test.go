@@ -0,0 +1,117 @@
+package ierror
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
+
+func TestGroup(t *testing.T) {
+	out := &syncBuffer{}
+	a := &Asserter{log: slog.New(slog.NewJSONHandler(out, nil))}
+	ctx := trace_context.SetTraceID(context.Background(), "parent")
+
+	group, groupCtx := a.NewGroup(ctx)
+	group.SetLimit(2)
+
+	var active, maxActive atomic.Int32
+	for i := 0; i < 6; i++ {
+		group.Go(func(ctx context.Context) error {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				current := maxActive.Load()
+				if n <= current || maxActive.CompareAndSwap(current, n) {
+					break
+				}
+			}
+
+			if i == 3 {
+				panic("boom")
+			}
+
+			select {
+			case <-time.After(20 * time.Millisecond):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
+
+	err := group.Wait()
+	if Code(err) != "E_PANIC" || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected panic error, got %v", err)
+	}
+	if groupCtx.Err() == nil {
+		t.Error("group context must be cancelled")
+	}
+	if maxActive.Load() > 2 {
+		t.Errorf("limit is exceeded: %d", maxActive.Load())
+	}
+	if logs := out.String(); !strings.Contains(logs, `"trace_id":"parent"`) || !strings.Contains(logs, "stacktrace") {
+		t.Errorf("panic must be logged with trace_id and stack: %s", logs)
+	}
+}
+
+func TestGroupCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	group, _ := (&Asserter{}).NewGroup(ctx)
+	cancel()
+
+	var started atomic.Bool
+	group.Go(func(ctx context.Context) error {
+		started.Store(true)
+		return nil
+	})
+
+	if err := group.Wait(); !errors.Is(err, context.Canceled) || started.Load() {
+		t.Errorf("fn must be skipped by cancelled group: %v %v", err, started.Load())
+	}
+}
+
+func TestGo(t *testing.T) {
+	out := &syncBuffer{}
+	a := &Asserter{log: slog.New(slog.NewJSONHandler(out, nil))}
+	ctx := trace_context.SetTraceID(context.Background(), "parent")
+
+	done := make(chan struct{})
+	a.Go(ctx, func(ctx context.Context) error {
+		defer close(done)
+		panic(errors.New("boom"))
+	})
+	<-done
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "goroutine panic") {
+		if time.Now().After(deadline) {
+			t.Fatal("panic is not logged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
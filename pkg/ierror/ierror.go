@@ -2,32 +2,262 @@ package ierror
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"goplate/http/server/interceptor"
+	"goplate/pkg/trace_logger"
 	"log/slog"
+	"net"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Category defines the log level of an error and whether it's reported
+	Category int
+
+	// Classify option for New, errors matched by errors.Is get the category
+	Classify struct {
+		Category Category
+		Errors   []error
+	}
+
+	// Exclude option for New, errors matched by errors.Is are ignored by Assert and Check
+	Exclude struct {
+		Errors []error
+	}
+
+	// Reporting option for New, bugs and incidents are sent to the Reporter
+	Reporting struct {
+		Reporter Reporter
+	}
+
+	// Asserter logs, classifies and reports errors
+	Asserter struct {
+		log      *slog.Logger
+		reporter Reporter
+		exclude  []error
+		classify []Classify
+	}
 
-	generic "github.com/rzaripov1990/genx"
+	categorized interface {
+		ErrorCategory() Category
+	}
+)
+
+const (
+	// CategoryUnknown is classified by rules and defaults, it's a bug when nothing matched
+	CategoryUnknown Category = iota
+	// CategoryExpected are validation errors, not found, cancelled requests: debug level, not reported
+	CategoryExpected
+	// CategoryTransient are timeouts and unavailable dependencies: warn level, not reported
+	CategoryTransient
+	// CategoryBug are unexpected errors: error level, reported
+	CategoryBug
+)
+
+var (
+	defaultAsserter atomic.Pointer[Asserter]
+	setDefault      sync.Once
 )
 
-var logger *slog.Logger
+// New creates an asserter, the first one becomes the default for package level functions.
+// Initialize it in main.go
+func New(log *slog.Logger, options ...any) *Asserter {
+	a := &Asserter{
+		log: log,
+	}
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Reporting:
+			a.reporter = typed.Reporter
+		case Exclude:
+			a.exclude = append(a.exclude, typed.Errors...)
+		case Classify:
+			a.classify = append(a.classify, typed)
+		}
+	}
+
+	setDefault.Do(func() {
+		defaultAsserter.Store(a)
+	})
 
-// Initialize only once, in main.go
-func New(l *slog.Logger) {
-	sync.OnceFunc(
-		func() {
-			logger = l
-		},
-	)
+	return a
 }
 
-// Check error not nil and log
+// Default returns the first asserter created by New, or an asserter writing to slog.Default()
+func Default() *Asserter {
+	if a := defaultAsserter.Load(); a != nil {
+		return a
+	}
+	return &Asserter{log: slog.Default()}
+}
+
+// Assert checks error not nil and logs it with the level of its category, returns false for nil or excluded error
 func Assert(ctx context.Context, err error, exclude ...error) (ok bool) {
-	ok = err != nil
-	if ok {
-		if !generic.In(err, exclude) {
-			logger.ErrorContext(ctx, "", "received error", err.Error())
-		} else {
-			ok = false
+	return Default().assert(ctx, err, "", exclude)
+}
+
+// Check is Assert with a message
+func Check(ctx context.Context, err error, msg string, exclude ...error) (ok bool) {
+	return Default().assert(ctx, err, msg, exclude)
+}
+
+// ReportIncident is interceptor.Config.Report of the default asserter
+func ReportIncident(ctx context.Context, incident interceptor.Incident) {
+	Default().ReportIncident(ctx, incident)
+}
+
+// Must returns value or panics with Error which records the caller location and stack
+func Must[T any](value T, err error) T {
+	if err != nil {
+		panic(&Error{
+			Code:    "E_MUST",
+			Message: "must: " + caller(1),
+			Cause:   err,
+			Stack:   interceptor.GetStacktrace(),
+		})
+	}
+	return value
+}
+
+// Assert checks error not nil and logs it with the level of its category, returns false for nil or excluded error
+func (a *Asserter) Assert(ctx context.Context, err error, exclude ...error) (ok bool) {
+	return a.assert(ctx, err, "", exclude)
+}
+
+// Check is Assert with a message
+func (a *Asserter) Check(ctx context.Context, err error, msg string, exclude ...error) (ok bool) {
+	return a.assert(ctx, err, msg, exclude)
+}
+
+// Classify returns the category of err: Error's own category, Classify options, known transient errors, bug
+func (a *Asserter) Classify(err error) Category {
+	var typed categorized
+	if errors.As(err, &typed) {
+		if category := typed.ErrorCategory(); category != CategoryUnknown {
+			return category
+		}
+	}
+
+	for _, rule := range a.classify {
+		if isAny(err, rule.Errors) {
+			return rule.Category
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CategoryExpected
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return CategoryTransient
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return CategoryTransient
+	}
+
+	return CategoryBug
+}
+
+func (a *Asserter) assert(ctx context.Context, err error, msg string, exclude []error) (ok bool) {
+	if err == nil || isAny(err, exclude) || isAny(err, a.exclude) {
+		return false
+	}
+
+	// skip assert and Assert/Check
+	location := caller(2)
+	category := a.Classify(err)
+
+	if a.log != nil {
+		trace_logger.L(ctx, a.log).Log(ctx, category.Level(), msg,
+			"received error", err.Error(),
+			"category", category.String(),
+			"caller", location,
+		)
+	}
+
+	if category == CategoryBug && a.reporter != nil {
+		stack := interceptor.GetStacktrace()
+		// drop the frame of Assert/Check
+		if len(stack) > 1 {
+			stack = stack[1:]
+		}
+
+		report := NewReport(ctx, err, stack)
+
+		values := map[string]any{"caller": location}
+		for key, value := range report.Values {
+			values[key] = value
+		}
+		report.Values = values
+		a.send(ctx, report)
+	}
+
+	return true
+}
+
+// ReportIncident is interceptor.Config.Report, sends incidents to the reporter of Reporting option
+func (a *Asserter) ReportIncident(ctx context.Context, incident interceptor.Incident) {
+	if a.reporter == nil || incident.Err == nil {
+		return
+	}
+
+	report := NewReport(ctx, incident.Err, incident.Stack)
+	report.Panic = incident.Panic
+	report.Method = incident.Method
+	report.Route = incident.Route
+	report.Status = incident.Status
+	report.Request = incident.Request
+
+	a.send(ctx, report)
+}
+
+func (a *Asserter) send(ctx context.Context, report Report) {
+	if err := a.reporter.Report(ctx, report); err != nil && a.log != nil {
+		a.log.ErrorContext(ctx, "", "report error", err.Error(), "fingerprint", report.Fingerprint)
+	}
+}
+
+func (c Category) Level() slog.Level {
+	switch c {
+	case CategoryExpected:
+		return slog.LevelDebug
+	case CategoryTransient:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+func (c Category) String() string {
+	switch c {
+	case CategoryExpected:
+		return "expected"
+	case CategoryTransient:
+		return "transient"
+	case CategoryBug:
+		return "bug"
+	}
+	return "unknown"
+}
+
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
 		}
 	}
-	return
+	return false
+}
+
+// caller returns file:line of the caller, skip 0 is the function calling caller
+func caller(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s:%d", file, line)
 }
@@ -0,0 +1,92 @@
+package ierror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var errNotFound = errors.New("not found")
+
+func TestAssert(t *testing.T) {
+	var out bytes.Buffer
+	memory := &memoryReporter{}
+	errCache := errors.New("cache miss")
+
+	a := New(
+		slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		Reporting{Reporter: memory},
+		Exclude{Errors: []error{errCache}},
+		Classify{Category: CategoryExpected, Errors: []error{errNotFound}},
+	)
+	ctx := context.Background()
+
+	if a.Assert(ctx, nil) {
+		t.Error("nil error must not be asserted")
+	}
+	if a.Assert(ctx, fmt.Errorf("get: %w", errCache)) || a.Assert(ctx, fmt.Errorf("get: %w", io.EOF), io.EOF) {
+		t.Error("wrapped excluded errors must be ignored")
+	}
+
+	cases := []struct {
+		err      error
+		category Category
+	}{
+		{fmt.Errorf("user: %w", errNotFound), CategoryExpected},
+		{context.DeadlineExceeded, CategoryTransient},
+		{NewError("E_UPSTREAM", http.StatusServiceUnavailable, ""), CategoryTransient},
+		{NewError("E_MODEL", http.StatusBadRequest, ""), CategoryExpected},
+		{errors.New("boom"), CategoryBug},
+	}
+	for _, c := range cases {
+		if category := a.Classify(c.err); category != c.category {
+			t.Errorf("%v: expected %s, got %s", c.err, c.category, category)
+		}
+	}
+
+	out.Reset()
+	if !a.Check(ctx, errors.New("boom"), "load user") {
+		t.Fatal("error must be asserted")
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["level"] != "ERROR" || record["msg"] != "load user" || record["category"] != "bug" ||
+		!strings.Contains(record["caller"].(string), "ierror_test.go") {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	if len(memory.reports) != 1 || memory.reports[0].Stack[0].Function != "goplate/pkg/ierror.TestAssert" {
+		t.Fatalf("only bug must be reported from the caller: %+v", memory.reports)
+	}
+
+	out.Reset()
+	a.Assert(ctx, fmt.Errorf("user: %w", errNotFound))
+	if !strings.Contains(out.String(), `"level":"DEBUG"`) || len(memory.reports) != 1 {
+		t.Errorf("expected error must be logged with debug level and not reported: %s", out.String())
+	}
+}
+
+func TestMust(t *testing.T) {
+	if Must(1, nil) != 1 {
+		t.Fatal("unexpected value")
+	}
+
+	defer func() {
+		err, ok := recover().(*Error)
+		if !ok || !errors.Is(err, io.EOF) || !strings.Contains(err.Message, "ierror_test.go") {
+			t.Errorf("unexpected panic: %v", err)
+		}
+	}()
+
+	Must(0, io.EOF)
+}
@@ -0,0 +1,131 @@
+package ierror
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"goplate/http/server/interceptor"
+	"maps"
+	"sync"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type (
+	// Reporter sends errors to an external error tracker
+	Reporter interface {
+		Report(ctx context.Context, report Report) error
+	}
+
+	// Report is an error prepared for a Reporter
+	Report struct {
+		Fingerprint string            `json:"fingerprint"`
+		Time        time.Time         `json:"time"`
+		TraceID     string            `json:"trace_id"`
+		Code        string            `json:"code,omitempty"`
+		Message     string            `json:"message"`
+		Panic       bool              `json:"panic,omitempty"`
+		Method      string            `json:"method,omitempty"`
+		Route       string            `json:"route,omitempty"`
+		Status      int               `json:"status,omitempty"`
+		Request     map[string]any    `json:"request,omitempty"`
+		Values      map[string]any    `json:"values,omitempty"`
+		Stack       interceptor.Stack `json:"stack,omitempty"`
+		// number of reports with the same fingerprint dropped by Dedup since the previous one
+		Suppressed int `json:"suppressed,omitempty"`
+	}
+
+	dedup struct {
+		next   Reporter
+		window time.Duration
+
+		mu   sync.Mutex
+		seen map[string]*dedupState
+	}
+
+	dedupState struct {
+		last       time.Time
+		suppressed int
+	}
+)
+
+// NewReport builds a report of err, the stack is taken from Error or passed stack
+func NewReport(ctx context.Context, err error, stack interceptor.Stack) Report {
+	report := Report{
+		Time:    time.Now(),
+		TraceID: trace_context.GetTraceID(ctx),
+		Message: err.Error(),
+		Stack:   stack,
+	}
+
+	var typed *Error
+	if errors.As(err, &typed) {
+		report.Code = typed.Code
+		// the error may be shared, e.g. a sentinel, so values are copied
+		report.Values = maps.Clone(typed.Values)
+		if len(report.Stack) == 0 {
+			report.Stack = typed.Stack
+		}
+	}
+
+	report.Fingerprint = Fingerprint(report.Code, report.Message, report.Stack)
+	return report
+}
+
+// Fingerprint groups reports by code and stack frames, lines are ignored to keep it stable between releases,
+// message is used only when there is no stack
+func Fingerprint(code, message string, stack interceptor.Stack) string {
+	hash := sha1.New()
+	hash.Write([]byte(code))
+
+	if len(stack) == 0 {
+		hash.Write([]byte(message))
+	}
+
+	for i := range stack {
+		hash.Write([]byte("\n" + stack[i].Function + "@" + stack[i].Path))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Dedup drops reports with the same fingerprint within window
+func Dedup(next Reporter, window time.Duration) Reporter {
+	return &dedup{
+		next:   next,
+		window: window,
+		seen:   make(map[string]*dedupState),
+	}
+}
+
+func (d *dedup) Report(ctx context.Context, report Report) error {
+	d.mu.Lock()
+
+	now := time.Now()
+	for fingerprint, state := range d.seen {
+		if now.Sub(state.last) >= d.window && state.suppressed == 0 {
+			delete(d.seen, fingerprint)
+		}
+	}
+
+	state, ok := d.seen[report.Fingerprint]
+	if ok && now.Sub(state.last) < d.window {
+		state.suppressed++
+		d.mu.Unlock()
+		return nil
+	}
+
+	if !ok {
+		state = &dedupState{}
+		d.seen[report.Fingerprint] = state
+	}
+
+	report.Suppressed = state.suppressed
+	state.last = now
+	state.suppressed = 0
+	d.mu.Unlock()
+
+	return d.next.Report(ctx, report)
+}
@@ -0,0 +1,179 @@
+package ierror
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type memoryReporter struct {
+	reports []Report
+}
+
+func (mr *memoryReporter) Report(_ context.Context, report Report) error {
+	mr.reports = append(mr.reports, report)
+	return nil
+}
+
+func TestDedup(t *testing.T) {
+	memory := &memoryReporter{}
+	reporter := Dedup(memory, 50*time.Millisecond)
+	ctx := context.Background()
+
+	err := NewError("E_DB", 500, "db is down")
+	for i := 0; i < 3; i++ {
+		_ = reporter.Report(ctx, NewReport(ctx, err, nil))
+	}
+	_ = reporter.Report(ctx, NewReport(ctx, errors.New("other"), nil))
+
+	if len(memory.reports) != 2 {
+		t.Fatalf("expected 2 reports, got %d", len(memory.reports))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	_ = reporter.Report(ctx, NewReport(ctx, err, nil))
+
+	if len(memory.reports) != 3 || memory.reports[2].Suppressed != 2 {
+		t.Fatalf("expected the report after window with 2 suppressed: %+v", memory.reports)
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	var (
+		auth  string
+		lines []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/42/envelope/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		auth = r.Header.Get("X-Sentry-Auth")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}))
+	defer server.Close()
+
+	reporter, err := NewSentryReporter(SentryConfig{
+		DSN: strings.Replace(server.URL, "http://", "http://public@", 1) + "/42",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := trace_context.SetTraceID(context.Background(), "abc")
+	sentinel := NewError("E_DB", 500, "db is down").With("db", "users")
+	report := NewReport(ctx, sentinel, nil)
+	report.Route = "/users"
+	report.Request = map[string]any{"phone": "****"}
+	report.Suppressed = 2
+
+	if err = reporter.Report(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+
+	// queued reports are sent by Close
+	if err = reporter.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = reporter.Report(ctx, report); !errors.Is(err, ErrReporterClosed) {
+		t.Errorf("expected closed reporter, got %v", err)
+	}
+	if len(sentinel.Values) != 1 {
+		t.Errorf("values of the error must not be changed: %v", sentinel.Values)
+	}
+
+	if !strings.Contains(auth, "sentry_key=public") {
+		t.Errorf("unexpected auth header: %s", auth)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected envelope of 3 lines, got %d", len(lines))
+	}
+
+	var event sentryEvent
+	if err = json.Unmarshal([]byte(lines[2]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Tags["trace_id"] != "abc" || event.Fingerprint[0] != report.Fingerprint || event.Request.Data["phone"] != "****" ||
+		event.Extra["db"] != "users" || event.Extra["suppressed"] != 2.0 {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if frames := event.Exception.Values[0].Stacktrace.Frames; frames[len(frames)-1].Function != "goplate/pkg/ierror.TestSentryReporter" {
+		t.Errorf("unexpected last frame: %+v", frames[len(frames)-1])
+	}
+}
+
+func TestSentryReporterQueue(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	reporter, err := NewSentryReporter(SentryConfig{
+		DSN:       strings.Replace(server.URL, "http://", "http://public@", 1) + "/42",
+		QueueSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	report := NewReport(ctx, errors.New("one"), nil)
+
+	// the first report is being sent, the second one waits in the queue
+	_ = reporter.Report(ctx, report)
+	time.Sleep(50 * time.Millisecond)
+	_ = reporter.Report(ctx, report)
+
+	if err = reporter.Report(ctx, report); !errors.Is(err, ErrReporterQueueFull) {
+		t.Errorf("expected full queue, got %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err = reporter.Close(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected timeout of close, got %v", err)
+	}
+
+	close(release)
+	if err = reporter.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+
+	reporter, err := NewFileReporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	_ = reporter.Report(ctx, NewReport(ctx, errors.New("one"), nil))
+	_ = reporter.Report(ctx, NewReport(ctx, errors.New("two"), nil))
+	_ = reporter.Close(ctx)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+}
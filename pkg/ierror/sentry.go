@@ -0,0 +1,317 @@
+package ierror
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// SentryReporter sends reports as Sentry envelopes over HTTP from a bounded queue,
+	// Close sends the queued reports
+	SentryReporter struct {
+		client      *http.Client
+		log         *slog.Logger
+		dsn         string
+		endpoint    string
+		auth        string
+		environment string
+		release     string
+
+		mu     sync.RWMutex
+		closed bool
+		queue  chan sentryEvent
+		done   chan struct{}
+	}
+
+	SentryConfig struct {
+		// https://<key>@<host>/<project_id>
+		DSN         string
+		Environment string
+		Release     string
+		// Optional. Default value http.Client with 10s timeout
+		Client *http.Client
+		// Reports waiting to be sent, new reports are dropped when the queue is full
+		// Optional. Default value 100
+		QueueSize int
+		// Failed sends are logged
+		// Optional. Default value slog.Default()
+		Log *slog.Logger
+	}
+
+	sentryEvent struct {
+		EventID     string            `json:"event_id"`
+		Timestamp   string            `json:"timestamp"`
+		Level       string            `json:"level"`
+		Platform    string            `json:"platform"`
+		Environment string            `json:"environment,omitempty"`
+		Release     string            `json:"release,omitempty"`
+		Transaction string            `json:"transaction,omitempty"`
+		Fingerprint []string          `json:"fingerprint"`
+		Tags        map[string]string `json:"tags,omitempty"`
+		Extra       map[string]any    `json:"extra,omitempty"`
+		Request     *sentryRequest    `json:"request,omitempty"`
+		Exception   sentryExceptions  `json:"exception"`
+	}
+
+	sentryRequest struct {
+		Method string         `json:"method,omitempty"`
+		URL    string         `json:"url,omitempty"`
+		Data   map[string]any `json:"data,omitempty"`
+	}
+
+	sentryExceptions struct {
+		Values []sentryException `json:"values"`
+	}
+
+	sentryException struct {
+		Type       string            `json:"type"`
+		Value      string            `json:"value"`
+		Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+	}
+
+	sentryStacktrace struct {
+		Frames []sentryFrame `json:"frames"`
+	}
+
+	sentryFrame struct {
+		Function string `json:"function"`
+		Filename string `json:"filename"`
+		Lineno   int    `json:"lineno,omitempty"`
+	}
+)
+
+const (
+	sentryClient           = "goplate/1.0"
+	defaultSentryQueueSize = 100
+)
+
+var (
+	ErrReporterQueueFull = errors.New("reporter queue is full")
+	ErrReporterClosed    = errors.New("reporter is closed")
+)
+
+func NewSentryReporter(cfg SentryConfig) (*SentryReporter, error) {
+	dsn, err := url.Parse(cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	project := strings.Trim(dsn.Path, "/")
+	if dsn.User == nil || dsn.User.Username() == "" || project == "" {
+		return nil, fmt.Errorf("invalid sentry dsn: %s", dsn.Redacted())
+	}
+
+	// project may be prefixed with the path of sentry installation
+	prefix, project := "", project
+	if i := strings.LastIndexByte(project, '/'); i >= 0 {
+		prefix, project = "/"+project[:i], project[i+1:]
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultSentryQueueSize
+	}
+	if cfg.Log == nil {
+		cfg.Log = slog.Default()
+	}
+
+	sr := &SentryReporter{
+		client:   client,
+		log:      cfg.Log,
+		dsn:      cfg.DSN,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", dsn.Scheme, dsn.Host, prefix, project),
+		auth: fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s",
+			sentryClient, dsn.User.Username()),
+		environment: cfg.Environment,
+		release:     cfg.Release,
+		queue:       make(chan sentryEvent, cfg.QueueSize),
+		done:        make(chan struct{}),
+	}
+
+	go sr.run()
+
+	return sr, nil
+}
+
+// Report queues the report, it doesn't wait for sentry
+func (sr *SentryReporter) Report(_ context.Context, report Report) error {
+	event := sr.event(report)
+
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+
+	if sr.closed {
+		return ErrReporterClosed
+	}
+
+	select {
+	case sr.queue <- event:
+		return nil
+	default:
+		return ErrReporterQueueFull
+	}
+}
+
+// Close stops accepting reports and waits until the queued ones are sent
+func (sr *SentryReporter) Close(ctx context.Context) error {
+	sr.mu.Lock()
+	if !sr.closed {
+		sr.closed = true
+		close(sr.queue)
+	}
+	sr.mu.Unlock()
+
+	select {
+	case <-sr.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (sr *SentryReporter) run() {
+	defer close(sr.done)
+
+	for event := range sr.queue {
+		if err := sr.send(event); err != nil {
+			sr.log.Error("", "report error", err.Error(), "fingerprint", event.Fingerprint[0])
+		}
+	}
+}
+
+func (sr *SentryReporter) send(event sentryEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	header, _ := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+		"dsn":      sr.dsn,
+	})
+	item, _ := json.Marshal(map[string]any{
+		"type":   "event",
+		"length": len(payload),
+	})
+
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(item)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	// the request of the report may be finished, the client timeout bounds the send
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, sr.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", sr.auth)
+
+	resp, err := sr.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("sentry: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (sr *SentryReporter) event(report Report) sentryEvent {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+
+	exceptionType := report.Code
+	switch {
+	case report.Panic:
+		exceptionType = "panic"
+	case exceptionType == "":
+		exceptionType = "error"
+	}
+
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(id[:]),
+		Timestamp:   report.Time.UTC().Format(time.RFC3339Nano),
+		Level:       "error",
+		Platform:    "go",
+		Environment: sr.environment,
+		Release:     sr.release,
+		Transaction: strings.TrimSpace(report.Method + " " + report.Route),
+		Fingerprint: []string{report.Fingerprint},
+		Tags: map[string]string{
+			"trace_id": report.TraceID,
+		},
+		// the values belong to the error, keys are added to a copy
+		Extra: maps.Clone(report.Values),
+		Exception: sentryExceptions{
+			Values: []sentryException{
+				{
+					Type:  exceptionType,
+					Value: report.Message,
+				},
+			},
+		},
+	}
+
+	if report.Code != "" {
+		event.Tags["code"] = report.Code
+	}
+	if report.Status != 0 {
+		event.Tags["status"] = strconv.Itoa(report.Status)
+	}
+	if report.Suppressed > 0 {
+		if event.Extra == nil {
+			event.Extra = make(map[string]any)
+		}
+		event.Extra["suppressed"] = report.Suppressed
+	}
+
+	if report.Method != "" || report.Request != nil {
+		event.Request = &sentryRequest{
+			Method: report.Method,
+			URL:    report.Route,
+			Data:   report.Request,
+		}
+	}
+
+	if len(report.Stack) > 0 {
+		// sentry expects the oldest frame first
+		frames := make([]sentryFrame, 0, len(report.Stack))
+		for i := len(report.Stack) - 1; i >= 0; i-- {
+			line, _ := strconv.Atoi(report.Stack[i].Line)
+			frames = append(frames, sentryFrame{
+				Function: report.Stack[i].Function,
+				Filename: report.Stack[i].Path,
+				Lineno:   line,
+			})
+		}
+		event.Exception.Values[0].Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+
+	return event
+}
@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Schedule returns the next run time after t
+	Schedule interface {
+		Next(t time.Time) time.Time
+		String() string
+	}
+
+	every struct {
+		interval time.Duration
+	}
+
+	// cron is a parsed expression "minute hour day-of-month month day-of-week", fields are bit sets
+	cron struct {
+		expr                          string
+		minute, hour, dom, month, dow uint64
+		// day matches any of dom or dow when both are restricted, as in cron(8),
+		// a field which matches every day ("*", "*/1", "?") isn't restricted
+		domAny, dowAny bool
+	}
+
+	field struct {
+		min, max int
+	}
+)
+
+var (
+	fields = [5]field{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+	descriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// ParseCron parses the standard 5 fields expression with *, lists, ranges and steps ("?" is * in day fields),
+// descriptors @hourly, @daily, @weekly, @monthly, @yearly and "@every <duration>"
+func ParseCron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+
+	if interval, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("cron %q: invalid interval", expr)
+		}
+		return every{interval: d}, nil
+	}
+
+	spec := expr
+	if descriptor, ok := descriptors[expr]; ok {
+		spec = descriptor
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron %q: expected %d fields", expr, len(fields))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		if part == "?" && (i == 2 || i == 4) {
+			part = "*"
+		}
+
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+
+	// sunday is 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cron{
+		expr:   expr,
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: bits[2] == allBits(fields[2]),
+		// sunday 7 is the same as 0
+		dowAny: bits[4]|1<<7 == allBits(fields[4]),
+	}, nil
+}
+
+func parseField(value string, f field) (bits uint64, err error) {
+	for _, item := range strings.Split(value, ",") {
+		rng, step := item, 1
+
+		if before, after, ok := strings.Cut(item, "/"); ok {
+			rng = before
+			if step, err = strconv.Atoi(after); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			before, after, _ := strings.Cut(rng, "-")
+			lo, err = strconv.Atoi(before)
+			if err == nil {
+				hi, err = strconv.Atoi(after)
+			}
+		default:
+			lo, err = strconv.Atoi(rng)
+			hi = lo
+			// "5/15" means from 5 to the max
+			if step > 1 {
+				hi = f.max
+			}
+		}
+
+		if err != nil || lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("invalid value %q", item)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+func allBits(f field) (bits uint64) {
+	for v := f.min; v <= f.max; v++ {
+		bits |= 1 << v
+	}
+	return bits
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(e.interval)
+}
+
+func (e every) String() string {
+	return "@every " + e.interval.String()
+}
+
+// Next finds the next matching minute, it's zero time when nothing matches within 5 years
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cron) String() string {
+	return c.expr
+}
@@ -0,0 +1,270 @@
+// The `scheduler` package runs cron and interval jobs as graceful processes
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goplate/pkg/graceful"
+	"goplate/pkg/ierror"
+	"goplate/pkg/trace_logger"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type (
+	// Cron option for Add, see ParseCron
+	Cron struct {
+		Expr string
+	}
+
+	// Every option for Add, the interval is counted from the end of the previous run
+	Every struct {
+		Interval time.Duration
+	}
+
+	// Jitter option for Add, random delay up to Value is added to every run
+	Jitter struct {
+		Value time.Duration
+	}
+
+	Job func(ctx context.Context) error
+
+	// JobInfo is the state of the job for admin endpoints
+	JobInfo struct {
+		Name         string    `json:"name"`
+		Schedule     string    `json:"schedule"`
+		Running      bool      `json:"running"`
+		Runs         int       `json:"runs"`
+		NextRun      time.Time `json:"nextRun"`
+		LastRun      time.Time `json:"lastRun"`
+		LastDuration string    `json:"lastDuration,omitempty"`
+		LastError    string    `json:"lastError,omitempty"`
+	}
+
+	Scheduler struct {
+		log *slog.Logger
+
+		mu   sync.Mutex
+		jobs []*job
+
+		// running jobs are waited on shutdown and cancelled by stop after the timeout,
+		// runs aren't started after stopped is set
+		runMu    sync.Mutex
+		stopped  bool
+		running  sync.WaitGroup
+		stopCtx  context.Context //nolint:containedctx
+		stop     context.CancelFunc
+		register sync.Once
+	}
+
+	job struct {
+		name     string
+		fn       Job
+		schedule Schedule
+		jitter   time.Duration
+
+		// guarded by Scheduler.mu
+		info JobInfo
+	}
+)
+
+var (
+	ErrNoSchedule = errors.New("scheduler: Cron or Every option is required")
+	ErrNeverRuns  = errors.New("scheduler: schedule has no next run")
+)
+
+func New(log *slog.Logger) *Scheduler {
+	stopCtx, stop := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		log:     trace_logger.Component(log, "scheduler"),
+		stopCtx: stopCtx,
+		stop:    stop,
+	}
+}
+
+// Add registers the job, options: Cron or Every, Jitter
+func (s *Scheduler) Add(name string, fn Job, options ...any) error {
+	j := &job{name: name, fn: fn}
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Cron:
+			schedule, err := ParseCron(typed.Expr)
+			if err != nil {
+				return err
+			}
+			j.schedule = schedule
+		case Every:
+			if typed.Interval <= 0 {
+				return ErrNoSchedule
+			}
+			j.schedule = every{interval: typed.Interval}
+		case Jitter:
+			j.jitter = typed.Value
+		}
+	}
+
+	if j.schedule == nil {
+		return ErrNoSchedule
+	}
+	// e.g. "0 0 30 2 *", the job would stop its process and the group
+	if j.schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("%w: %s", ErrNeverRuns, j.schedule)
+	}
+
+	j.info = JobInfo{Name: name, Schedule: j.schedule.String()}
+
+	s.mu.Lock()
+	s.jobs = append(s.jobs, j)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Process runs every job as graceful.Process, they stop on shutdown,
+// running jobs are waited in graceful.PhaseDrain and cancelled when its timeout expires
+func (s *Scheduler) Process(group *graceful.CloseGroup) {
+	s.mu.Lock()
+	jobs := append([]*job(nil), s.jobs...)
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		graceful.Process(group, j, s.loop, graceful.Name{Value: "job " + j.name})
+	}
+
+	s.register.Do(func() {
+		graceful.Close(group, s, wait, graceful.PhaseDrain, graceful.Name{Value: "scheduler"})
+	})
+}
+
+// Jobs returns the state of all jobs
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]JobInfo, len(s.jobs))
+	for i, j := range s.jobs {
+		jobs[i] = j.info
+	}
+	return jobs
+}
+
+func wait(ctx context.Context, s *Scheduler) error {
+	s.runMu.Lock()
+	s.stopped = true
+	s.runMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.stop()
+		return ctx.Err()
+	}
+}
+
+// loop runs the job by schedule, the next run is planned after the current one is finished,
+// so runs never overlap and missed ones are skipped
+func (s *Scheduler) loop(ctx context.Context, j *job) error {
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			// the end of the process cancels the group, so the job waits for shutdown
+			s.log.WarnContext(ctx, "Job has no next run", "name", j.name)
+			<-ctx.Done()
+			return nil
+		}
+		if j.jitter > 0 {
+			next = next.Add(rand.N(j.jitter))
+		}
+
+		s.update(j, func(info *JobInfo) {
+			info.NextRun = next
+		})
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the run isn't interrupted by shutdown, see wait
+		if !s.begin() {
+			return nil
+		}
+		s.run(j)
+		s.running.Done()
+	}
+}
+
+// begin adds the run to waited ones unless wait is started
+func (s *Scheduler) begin() bool {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if s.stopped {
+		return false
+	}
+	s.running.Add(1)
+	return true
+}
+
+func (s *Scheduler) run(j *job) {
+	ctx := trace_context.WithTraceID(s.stopCtx)
+	log := trace_logger.L(ctx, s.log)
+	start := time.Now()
+
+	s.update(j, func(info *JobInfo) {
+		info.Running = true
+		info.LastRun = start
+	})
+
+	// panic is recovered, logged and reported by the group
+	group, _ := ierror.NewGroup(ctx)
+	group.Go(j.fn)
+	err := group.Wait()
+
+	duration := time.Since(start)
+
+	s.update(j, func(info *JobInfo) {
+		info.Running = false
+		info.Runs++
+		info.LastDuration = duration.String()
+		info.LastError = ""
+		if err != nil {
+			info.LastError = err.Error()
+		}
+	})
+
+	// panic is logged already
+	if err != nil && ierror.Code(err) != "E_PANIC" {
+		ierror.Check(ctx, err, "job failed")
+	}
+
+	log.InfoContext(ctx, "Job finished", "name", j.name, "duration", duration.String(), "failed", err != nil)
+}
+
+func (s *Scheduler) update(j *job, fn func(info *JobInfo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&j.info)
+}
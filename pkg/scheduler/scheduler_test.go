@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"goplate/pkg/graceful"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+func TestScheduler(t *testing.T) {
+	s := New(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	var (
+		mu     sync.Mutex
+		traces = make(map[string]bool)
+		done   bool
+	)
+	err := s.Add("sync", func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		traces[trace_context.GetTraceID(ctx)] = true
+		done = ctx.Err() == nil
+		mu.Unlock()
+		return nil
+	}, Every{Interval: 5 * time.Millisecond}, Jitter{Value: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("broken", func(context.Context) error { panic("boom") }, Cron{Expr: "@every 5ms"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("nothing", func(context.Context) error { return nil }); err != ErrNoSchedule {
+		t.Fatalf("expected ErrNoSchedule, got %v", err)
+	}
+	if err := s.Add("never", func(context.Context) error { return nil }, Cron{Expr: "0 0 30 2 *"}); !errors.Is(err, ErrNeverRuns) {
+		t.Fatalf("expected ErrNeverRuns, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, group := graceful.Prepare(ctx, graceful.Notify{})
+	s.Process(group)
+
+	time.Sleep(70 * time.Millisecond)
+	cancel()
+	if err := group.Wait(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := s.Jobs()
+	if len(jobs) != 2 || jobs[0].Runs < 2 || jobs[0].Running || !strings.Contains(jobs[1].LastError, "boom") {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(traces) != jobs[0].Runs || !done {
+		t.Errorf("every run must have own trace_id and finish on shutdown: %d runs, %d traces", jobs[0].Runs, len(traces))
+	}
+}
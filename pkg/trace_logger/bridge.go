@@ -0,0 +1,160 @@
+package trace_logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	fiberlog "github.com/gofiber/fiber/v2/log"
+)
+
+type (
+	// FiberLogger routes fiber's internal log (github.com/gofiber/fiber/v2/log) into slog
+	FiberLogger struct {
+		log   *slog.Logger
+		ctx   context.Context //nolint:containedctx
+		level slog.Level
+	}
+
+	// PrintfLogger routes Printf style loggers (fasthttp.Logger) into slog with the fixed level
+	PrintfLogger struct {
+		log   *slog.Logger
+		level slog.Level
+	}
+)
+
+var _ fiberlog.AllLogger = (*FiberLogger)(nil)
+
+const (
+	levelTrace = slog.LevelDebug - 4
+	levelFatal = slog.LevelError + 4
+	levelPanic = slog.LevelError + 8
+)
+
+// Bridge routes stdlib `log`, `slog.Default()` and fiber's internal log into log
+func Bridge(log *slog.Logger) {
+	// stdlib log package writes into the default slog handler with info level
+	slog.SetDefault(log)
+	fiberlog.SetLogger(NewFiberLogger(log))
+}
+
+func NewFiberLogger(log *slog.Logger) *FiberLogger {
+	return &FiberLogger{
+		log:   log,
+		ctx:   context.Background(),
+		level: levelTrace,
+	}
+}
+
+func NewPrintfLogger(log *slog.Logger, level slog.Level) *PrintfLogger {
+	return &PrintfLogger{
+		log:   log,
+		level: level,
+	}
+}
+
+func (pl *PrintfLogger) Printf(format string, args ...any) {
+	pl.log.Log(context.Background(), pl.level, fmt.Sprintf(format, args...))
+}
+
+func (fl *FiberLogger) logv(level slog.Level, args []any) {
+	if level < fl.level {
+		return
+	}
+	fl.log.Log(fl.ctx, level, fmt.Sprint(args...))
+}
+
+func (fl *FiberLogger) logf(level slog.Level, format string, args []any) {
+	if level < fl.level {
+		return
+	}
+	fl.log.Log(fl.ctx, level, fmt.Sprintf(format, args...))
+}
+
+func (fl *FiberLogger) logw(level slog.Level, msg string, keysAndValues []any) {
+	if level < fl.level {
+		return
+	}
+	fl.log.Log(fl.ctx, level, msg, keysAndValues...)
+}
+
+func (fl *FiberLogger) Trace(v ...any) { fl.logv(levelTrace, v) }
+func (fl *FiberLogger) Debug(v ...any) { fl.logv(slog.LevelDebug, v) }
+func (fl *FiberLogger) Info(v ...any)  { fl.logv(slog.LevelInfo, v) }
+func (fl *FiberLogger) Warn(v ...any)  { fl.logv(slog.LevelWarn, v) }
+func (fl *FiberLogger) Error(v ...any) { fl.logv(slog.LevelError, v) }
+
+func (fl *FiberLogger) Fatal(v ...any) {
+	fl.logv(levelFatal, v)
+	os.Exit(1)
+}
+
+func (fl *FiberLogger) Panic(v ...any) {
+	fl.logv(levelPanic, v)
+	panic(fmt.Sprint(v...))
+}
+
+func (fl *FiberLogger) Tracef(format string, v ...any) { fl.logf(levelTrace, format, v) }
+func (fl *FiberLogger) Debugf(format string, v ...any) { fl.logf(slog.LevelDebug, format, v) }
+func (fl *FiberLogger) Infof(format string, v ...any)  { fl.logf(slog.LevelInfo, format, v) }
+func (fl *FiberLogger) Warnf(format string, v ...any)  { fl.logf(slog.LevelWarn, format, v) }
+func (fl *FiberLogger) Errorf(format string, v ...any) { fl.logf(slog.LevelError, format, v) }
+
+func (fl *FiberLogger) Fatalf(format string, v ...any) {
+	fl.logf(levelFatal, format, v)
+	os.Exit(1)
+}
+
+func (fl *FiberLogger) Panicf(format string, v ...any) {
+	fl.logf(levelPanic, format, v)
+	panic(fmt.Sprintf(format, v...))
+}
+
+func (fl *FiberLogger) Tracew(msg string, kv ...any) { fl.logw(levelTrace, msg, kv) }
+func (fl *FiberLogger) Debugw(msg string, kv ...any) { fl.logw(slog.LevelDebug, msg, kv) }
+func (fl *FiberLogger) Infow(msg string, kv ...any)  { fl.logw(slog.LevelInfo, msg, kv) }
+func (fl *FiberLogger) Warnw(msg string, kv ...any)  { fl.logw(slog.LevelWarn, msg, kv) }
+func (fl *FiberLogger) Errorw(msg string, kv ...any) { fl.logw(slog.LevelError, msg, kv) }
+
+func (fl *FiberLogger) Fatalw(msg string, kv ...any) {
+	fl.logw(levelFatal, msg, kv)
+	os.Exit(1)
+}
+
+func (fl *FiberLogger) Panicw(msg string, kv ...any) {
+	fl.logw(levelPanic, msg, kv)
+	panic(msg)
+}
+
+// WithContext returns a logger with trace_id of ctx
+func (fl *FiberLogger) WithContext(ctx context.Context) fiberlog.CommonLogger {
+	return &FiberLogger{
+		log:   L(ctx, fl.log),
+		ctx:   ctx,
+		level: fl.level,
+	}
+}
+
+func (fl *FiberLogger) SetLevel(level fiberlog.Level) {
+	switch level {
+	case fiberlog.LevelTrace:
+		fl.level = levelTrace
+	case fiberlog.LevelDebug:
+		fl.level = slog.LevelDebug
+	case fiberlog.LevelInfo:
+		fl.level = slog.LevelInfo
+	case fiberlog.LevelWarn:
+		fl.level = slog.LevelWarn
+	case fiberlog.LevelError:
+		fl.level = slog.LevelError
+	case fiberlog.LevelFatal:
+		fl.level = levelFatal
+	case fiberlog.LevelPanic:
+		fl.level = levelPanic
+	}
+}
+
+// SetOutput is ignored, the output is defined by the slog handler
+func (fl *FiberLogger) SetOutput(_ io.Writer) {}
@@ -0,0 +1,70 @@
+package trace_logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	fiberlog "github.com/gofiber/fiber/v2/log"
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+func TestBridge(t *testing.T) {
+	defaultLog, defaultFiber := slog.Default(), fiberlog.DefaultLogger()
+	t.Cleanup(func() {
+		slog.SetDefault(defaultLog)
+		fiberlog.SetLogger(defaultFiber)
+	})
+
+	var buf bytes.Buffer
+	ctx := trace_context.SetTraceID(context.Background(), "abc")
+	Bridge(L(ctx, slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: levelTrace}))))
+
+	log.Printf("std %d", 1)
+	slog.Default().Debug("default")
+
+	fiberlog.Trace("trace")
+	fiberlog.Warnf("warn %s", "f")
+	fiberlog.Errorw("error", "key", "value")
+	fiberlog.SetLevel(fiberlog.LevelWarn)
+	fiberlog.Info("skipped")
+	fiberlog.WithContext(trace_context.SetTraceID(context.Background(), "other")).Error("with context")
+
+	NewPrintfLogger(slog.Default(), slog.LevelWarn).Printf("printf %s", "p")
+
+	expected := []struct{ level, msg, traceID string }{
+		{"INFO", "std 1", "abc"},
+		{"DEBUG", "default", "abc"},
+		{"DEBUG-4", "trace", "abc"},
+		{"WARN", "warn f", "abc"},
+		{"ERROR", "error", "abc"},
+		{"ERROR", "with context", "other"},
+		{"WARN", "printf p", "abc"},
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d records, got %d: %s", len(expected), len(lines), buf.String())
+	}
+
+	for i, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+
+		// WithContext adds trace_id of its ctx after the bridged one, the last key wins
+		traceID := record[trace_context.TraceIDKeyName]
+		if record["level"] != expected[i].level || record["msg"] != expected[i].msg || traceID != expected[i].traceID {
+			t.Errorf("unexpected record %d: %s", i, line)
+		}
+	}
+
+	if !strings.Contains(lines[4], `"key":"value"`) {
+		t.Errorf("expected attrs of Errorw: %s", lines[4])
+	}
+}
@@ -0,0 +1,199 @@
+package trace_logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// NamedLevels option for New, level overrides per component name prefix,
+	// e.g. "http.client=debug,interceptor=warn"
+	NamedLevels struct {
+		Value string
+	}
+
+	levelRegistry struct {
+		mu        sync.Mutex // serializes writers, readers use the snapshot
+		base      slog.LevelVar
+		overrides atomic.Pointer[map[string]slog.Level]
+	}
+
+	// levelHandler checks the level of the component, the name is taken from ComponentKeyName attr
+	levelHandler struct {
+		next slog.Handler
+		name string
+	}
+)
+
+const (
+	ComponentKeyName = "component"
+)
+
+var (
+	levels = newLevelRegistry()
+)
+
+func newLevelRegistry() *levelRegistry {
+	lr := &levelRegistry{}
+	lr.overrides.Store(&map[string]slog.Level{})
+	return lr
+}
+
+// level returns the override of the longest matching name prefix ("http.client" -> "http") or the base level
+func (lr *levelRegistry) level(name string) slog.Level {
+	overrides := *lr.overrides.Load()
+
+	for len(overrides) > 0 && name != "" {
+		if level, ok := overrides[name]; ok {
+			return level
+		}
+
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+
+	return lr.base.Level()
+}
+
+func (lr *levelRegistry) store(update func(overrides map[string]slog.Level)) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	overrides := make(map[string]slog.Level)
+	for name, level := range *lr.overrides.Load() {
+		overrides[name] = level
+	}
+	update(overrides)
+
+	lr.overrides.Store(&overrides)
+}
+
+// Named returns the logger created by New with the component attribute
+func Named(name string) *slog.Logger {
+	if logger == nil {
+		return Component(slog.Default(), name)
+	}
+	return Component(logger, name)
+}
+
+// Component adds the component attribute to log, its level may be overridden by SetLevel
+func Component(log *slog.Logger, name string) *slog.Logger {
+	return log.With(ComponentKeyName, name)
+}
+
+// SetLevel changes the level of the component name prefix at runtime,
+// empty name changes the base level, empty level removes the override
+func SetLevel(name, level string) error {
+	if name == "" {
+		parsed, err := parseLevel(level)
+		if err != nil {
+			return err
+		}
+
+		levels.base.Set(parsed)
+		return nil
+	}
+
+	if level == "" {
+		levels.store(func(overrides map[string]slog.Level) {
+			delete(overrides, name)
+		})
+		return nil
+	}
+
+	parsed, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	levels.store(func(overrides map[string]slog.Level) {
+		overrides[name] = parsed
+	})
+	return nil
+}
+
+// SetLevels replaces all overrides with the value like "http.client=debug,interceptor=warn"
+func SetLevels(value string) error {
+	parsed := make(map[string]slog.Level)
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, level, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return fmt.Errorf("invalid level override: %q", part)
+		}
+
+		l, err := parseLevel(strings.TrimSpace(level))
+		if err != nil {
+			return err
+		}
+		parsed[name] = l
+	}
+
+	levels.store(func(overrides map[string]slog.Level) {
+		clear(overrides)
+		for name, level := range parsed {
+			overrides[name] = level
+		}
+	})
+	return nil
+}
+
+// GetLevels returns the overrides, the base level is under the empty name
+func GetLevels() map[string]string {
+	overrides := *levels.overrides.Load()
+
+	result := make(map[string]string, len(overrides)+1)
+	result[""] = levels.base.Level().String()
+	for name, level := range overrides {
+		result[name] = level.String()
+	}
+	return result
+}
+
+func parseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug", "info", "warn", "warning", "error":
+		return parseStringLevel(level), nil
+	}
+	return 0, fmt.Errorf("unknown log level: %q", level)
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= levels.level(h.name)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.next.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+
+	for _, attr := range attrs {
+		if attr.Key == ComponentKeyName {
+			clone.name = attr.Value.String()
+		}
+	}
+
+	return &clone
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	return &clone
+}
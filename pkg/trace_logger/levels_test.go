@@ -0,0 +1,43 @@
+package trace_logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestNamedLevels(t *testing.T) {
+	New("error", false, NamedLevels{Value: "http=debug,http.server=warn"})
+	defer SetLevels("")
+
+	ctx := context.Background()
+	cases := []struct {
+		name    string
+		level   slog.Level
+		enabled bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"http", slog.LevelDebug, true},
+		{"http.client", slog.LevelDebug, true},
+		{"http.server", slog.LevelInfo, false},
+		{"http.server.fasthttp", slog.LevelWarn, true},
+		{"httpx", slog.LevelDebug, false},
+	}
+
+	for _, c := range cases {
+		if enabled := Named(c.name).Enabled(ctx, c.level); enabled != c.enabled {
+			t.Errorf("%q %s: expected %v, got %v", c.name, c.level, c.enabled, enabled)
+		}
+	}
+
+	if err := SetLevel("http.client", "error"); err != nil {
+		t.Fatal(err)
+	}
+	if Named("http.client").Enabled(ctx, slog.LevelWarn) {
+		t.Error("runtime override is not applied")
+	}
+
+	if err := SetLevels("http=verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
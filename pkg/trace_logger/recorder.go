@@ -0,0 +1,277 @@
+package trace_logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type (
+	// FlightRecorder option for New, keeps the last Size records of every trace_id
+	// (including levels below the configured output level) for at most Traces trace_ids.
+	FlightRecorder struct {
+		Size   int
+		Traces int
+	}
+
+	// Entry is a buffered log record in a JSON friendly form
+	Entry struct {
+		Time    time.Time      `json:"time"`
+		Level   string         `json:"level"`
+		Message string         `json:"msg"`
+		Attrs   map[string]any `json:"attrs,omitempty"`
+	}
+
+	recorderStore struct {
+		mu     sync.Mutex
+		size   int
+		traces int
+		rings  map[string]*ring
+		order  []string // trace_ids from the oldest to the newest
+	}
+
+	ring struct {
+		entries []Entry
+		next    int
+		full    bool
+	}
+
+	// marks records which must not be buffered
+	skipRecordKey struct{}
+
+	recorderHandler struct {
+		next    slog.Handler
+		store   *recorderStore
+		traceID string
+		attrs   []slog.Attr
+		groups  []string
+	}
+)
+
+const (
+	defaultRecorderSize   = 64
+	defaultRecorderTraces = 1024
+)
+
+var (
+	recorder *recorderStore
+)
+
+func newRecorderStore(option FlightRecorder) *recorderStore {
+	if option.Size <= 0 {
+		option.Size = defaultRecorderSize
+	}
+	if option.Traces <= 0 {
+		option.Traces = defaultRecorderTraces
+	}
+
+	return &recorderStore{
+		size:   option.Size,
+		traces: option.Traces,
+		rings:  make(map[string]*ring, option.Traces),
+	}
+}
+
+func (rs *recorderStore) add(traceID string, entry Entry) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.rings[traceID]
+	if !ok {
+		// evict the oldest trace_id when the store is full
+		if len(rs.order) >= rs.traces {
+			delete(rs.rings, rs.order[0])
+			rs.order = rs.order[1:]
+		}
+
+		r = &ring{entries: make([]Entry, rs.size)}
+		rs.rings[traceID] = r
+		rs.order = append(rs.order, traceID)
+	}
+
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (rs *recorderStore) get(traceID string) []Entry {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.rings[traceID]
+	if !ok {
+		return nil
+	}
+
+	if !r.full {
+		return append([]Entry(nil), r.entries[:r.next]...)
+	}
+
+	return append(append([]Entry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// Records returns buffered records of the trace_id from the oldest to the newest,
+// nil if flight recorder is disabled or nothing was recorded
+func Records(traceID string) []Entry {
+	if recorder == nil {
+		return nil
+	}
+	return recorder.get(traceID)
+}
+
+// Dump writes all buffered records of the trace_id from ctx as a single error log entry,
+// Schema option defines the trace keys of the entry (SchemaDefault by default)
+func Dump(ctx context.Context, log *slog.Logger, options ...any) {
+	if log == nil {
+		return
+	}
+
+	schema := SchemaDefault
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case Schema:
+			schema = typed
+		}
+	}
+
+	traceID := traceIDFromContext(ctx)
+	if traceID == "" {
+		return
+	}
+
+	entries := Records(traceID)
+	if len(entries) == 0 {
+		return
+	}
+
+	// the dump itself must not get into the buffer
+	ctx = context.WithValue(ctx, skipRecordKey{}, true)
+
+	schema.L(ctx, log).LogAttrs(ctx, slog.LevelError, "Flight recorder", slog.Any("records", entries))
+}
+
+func traceIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	// trace_context.GetTraceID generates a new value when it's absent, so read ctx directly
+	traceID, _ := ctx.Value(trace_context.TraceKeyInCtx).(string)
+	return traceID
+}
+
+// Enabled is true for the output level and for every level of records which are buffered by trace_id
+func (h *recorderHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.next.Enabled(ctx, level) {
+		return true
+	}
+
+	if h.store == nil || (ctx != nil && ctx.Value(skipRecordKey{}) != nil) {
+		return false
+	}
+	return h.traceID != "" || traceIDFromContext(ctx) != ""
+}
+
+func (h *recorderHandler) Handle(ctx context.Context, record slog.Record) error {
+	traceID := h.traceID
+	if traceID == "" {
+		traceID = traceIDFromContext(ctx)
+	}
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		if isTraceIDKey(attr.Key) && traceID == "" {
+			traceID = attr.Value.String()
+		}
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	if skip, _ := ctx.Value(skipRecordKey{}).(bool); traceID != "" && !skip {
+		h.store.add(traceID, Entry{
+			Time:    record.Time,
+			Level:   record.Level.String(),
+			Message: record.Message,
+			Attrs:   h.entryAttrs(attrs),
+		})
+	}
+
+	if !h.next.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+func (h *recorderHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	clone.attrs = append(clone.attrs[:len(clone.attrs):len(clone.attrs)], h.grouped(attrs)...)
+
+	for _, attr := range attrs {
+		if isTraceIDKey(attr.Key) && len(h.groups) == 0 {
+			clone.traceID = attr.Value.String()
+		}
+	}
+
+	return &clone
+}
+
+func (h *recorderHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.groups = append(clone.groups[:len(clone.groups):len(clone.groups)], name)
+	return &clone
+}
+
+// grouped nests attrs into the currently opened groups
+func (h *recorderHandler) grouped(attrs []slog.Attr) []slog.Attr {
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		values := make([]any, len(attrs))
+		for j := range attrs {
+			values[j] = attrs[j]
+		}
+		attrs = []slog.Attr{slog.Group(h.groups[i], values...)}
+	}
+	return attrs
+}
+
+func (h *recorderHandler) entryAttrs(attrs []slog.Attr) map[string]any {
+	all := append(h.attrs[:len(h.attrs):len(h.attrs)], h.grouped(attrs)...)
+	if len(all) == 0 {
+		return nil
+	}
+
+	result := make(map[string]any, len(all))
+	for _, attr := range all {
+		if isTraceIDKey(attr.Key) {
+			continue
+		}
+		result[attr.Key] = attrValue(attr.Value)
+	}
+	return result
+}
+
+func attrValue(value slog.Value) any {
+	value = value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return value.Any()
+	}
+
+	group := value.Group()
+	result := make(map[string]any, len(group))
+	for _, attr := range group {
+		result[attr.Key] = attrValue(attr.Value)
+	}
+	return result
+}
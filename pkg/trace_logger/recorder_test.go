@@ -0,0 +1,66 @@
+package trace_logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	log := New("error", false, FlightRecorder{Size: 2, Traces: 1})
+
+	ctx := trace_context.SetTraceID(context.Background(), "first")
+	L(ctx, log).Debug("one")
+	L(ctx, log).Debug("two")
+	L(ctx, log).Info("three", "key", "value")
+
+	entries := Records("first")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(entries))
+	}
+	if entries[0].Message != "two" || entries[1].Message != "three" {
+		t.Fatalf("unexpected order: %+v", entries)
+	}
+	if entries[1].Attrs["key"] != "value" {
+		t.Fatalf("unexpected attrs: %+v", entries[1].Attrs)
+	}
+
+	Dump(ctx, log)
+	if len(Records("first")) != 2 {
+		t.Fatal("dump must not be recorded")
+	}
+
+	other := trace_context.SetTraceID(context.Background(), "second")
+	L(other, log).Debug("four")
+
+	if Records("first") != nil {
+		t.Fatal("oldest trace_id must be evicted")
+	}
+	if len(Records("second")) != 1 {
+		t.Fatal("expected record of the second trace_id")
+	}
+}
+
+func TestFlightRecorderEnabled(t *testing.T) {
+	log := New("error", false, FlightRecorder{Size: 2, Traces: 1})
+	ctx := context.Background()
+
+	if log.Enabled(ctx, slog.LevelDebug) {
+		t.Error("debug without trace_id must be disabled")
+	}
+	if !log.Enabled(ctx, slog.LevelError) {
+		t.Error("output level must be enabled")
+	}
+	if !log.Enabled(trace_context.SetTraceID(ctx, "first"), slog.LevelDebug) {
+		t.Error("debug with trace_id in ctx must be recorded")
+	}
+	if !L(ctx, log).Enabled(ctx, slog.LevelDebug) {
+		t.Error("debug with trace_id attr must be recorded")
+	}
+
+	if New("error", false).Enabled(trace_context.SetTraceID(ctx, "first"), slog.LevelDebug) {
+		t.Error("debug without recorder must be disabled")
+	}
+}
@@ -0,0 +1,297 @@
+package trace_logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+type (
+	// Schema defines attribute keys of request logs in the interceptor (Server) and http client (Client),
+	// empty key means the attribute is not logged
+	Schema struct {
+		Name       string
+		TraceID    string
+		SpanID     string
+		TraceFlags string
+		Server     HTTPKeys
+		Client     HTTPKeys
+	}
+
+	HTTPKeys struct {
+		Method string
+		URL    string
+		// when Query is empty the query string is appended to Path
+		Path  string
+		Query string
+		// key ending with "." is a prefix of the attribute per header, otherwise all headers are joined into one string
+		RequestHeaders string
+		// lowercase header names in per header attributes
+		LowerHeaders bool
+		RequestBody  string
+		// request bound by reqresp.Bind, it's masked as the request body
+		Payload         string
+		StatusCode      string
+		ContentType     string
+		ResponseBody    string
+		Error           string
+		Stacktrace      string
+		Duration        string
+		DurationNanos   string
+		DurationSeconds string
+		Slow            string
+	}
+
+	// Span is the position of the current request in the distributed trace (W3C traceparent)
+	Span struct {
+		ID    string
+		Flags string
+	}
+
+	spanKey struct{}
+)
+
+var (
+	// SchemaDefault is the format used before schemas were introduced
+	SchemaDefault = Schema{
+		Name:    "default",
+		TraceID: trace_context.TraceIDKeyName,
+		Server: HTTPKeys{
+			Method:         "method",
+			Path:           "query",
+			RequestHeaders: "headers",
+			RequestBody:    "body",
+			Payload:        "payload",
+			StatusCode:     "code",
+			ContentType:    "content-type",
+			ResponseBody:   "response",
+			Error:          "error",
+			Stacktrace:     "stacktrace",
+			Duration:       "duration",
+			DurationNanos:  "duration_nanosec",
+			Slow:           "slow",
+		},
+		Client: HTTPKeys{
+			Method:         "request.method",
+			URL:            "request.url",
+			RequestHeaders: "request.header.",
+			StatusCode:     "response.code",
+			ContentType:    "response.content_type",
+			ResponseBody:   "response.body",
+			Error:          "response.error",
+			Duration:       "response.duration",
+			DurationNanos:  "response.duration_nanosec",
+		},
+	}
+
+	// SchemaOTel follows OpenTelemetry semantic conventions
+	SchemaOTel = Schema{
+		Name:       "otel",
+		TraceID:    "trace_id",
+		SpanID:     "span_id",
+		TraceFlags: "trace_flags",
+		Server:     otelKeys,
+		Client:     otelKeys,
+	}
+
+	// SchemaECS follows Elastic Common Schema
+	SchemaECS = Schema{
+		Name:    "ecs",
+		TraceID: "trace.id",
+		SpanID:  "span.id",
+		Server:  ecsKeys,
+		Client:  ecsKeys,
+	}
+
+	otelKeys = HTTPKeys{
+		Method:          "http.request.method",
+		URL:             "url.full",
+		Path:            "url.path",
+		Query:           "url.query",
+		RequestHeaders:  "http.request.header.",
+		LowerHeaders:    true,
+		RequestBody:     "http.request.body.content",
+		Payload:         "http.request.payload",
+		StatusCode:      "http.response.status_code",
+		ContentType:     "http.response.header.content-type",
+		ResponseBody:    "http.response.body.content",
+		Error:           "exception.message",
+		Stacktrace:      "exception.stacktrace",
+		DurationSeconds: "http.request.duration",
+		Slow:            "slow",
+	}
+
+	ecsKeys = HTTPKeys{
+		Method:         "http.request.method",
+		URL:            "url.full",
+		Path:           "url.path",
+		Query:          "url.query",
+		RequestHeaders: "http.request.headers.",
+		LowerHeaders:   true,
+		RequestBody:    "http.request.body.content",
+		Payload:        "http.request.payload",
+		StatusCode:     "http.response.status_code",
+		ContentType:    "http.response.mime_type",
+		ResponseBody:   "http.response.body.content",
+		Error:          "error.message",
+		Stacktrace:     "error.stack_trace",
+		DurationNanos:  "event.duration",
+		Slow:           "slow",
+	}
+
+	schemas = map[string]Schema{
+		SchemaDefault.Name: SchemaDefault,
+		SchemaOTel.Name:    SchemaOTel,
+		SchemaECS.Name:     SchemaECS,
+	}
+)
+
+// SchemaByName returns the preset by its name: default, otel or ecs
+func SchemaByName(name string) (Schema, bool) {
+	schema, ok := schemas[strings.ToLower(name)]
+	return schema, ok
+}
+
+// isTraceIDKey reports whether the key is the trace id key of a preset
+func isTraceIDKey(key string) bool {
+	for _, schema := range schemas {
+		if schema.TraceID != "" && key == schema.TraceID {
+			return true
+		}
+	}
+	return false
+}
+
+// L adds trace attributes of ctx to log with keys of the schema
+func (s Schema) L(ctx context.Context, log *slog.Logger) *slog.Logger {
+	var args []any
+
+	if s.TraceID != "" {
+		args = append(args, s.TraceID, trace_context.GetTraceID(ctx))
+	}
+
+	if span, ok := GetSpan(ctx); ok {
+		if s.SpanID != "" {
+			args = append(args, s.SpanID, span.ID)
+		}
+		if s.TraceFlags != "" {
+			args = append(args, s.TraceFlags, span.Flags)
+		}
+	}
+
+	return log.With(args...)
+}
+
+// Append adds the attribute when the key is defined
+func Append(attrs []slog.Attr, key string, value any) []slog.Attr {
+	if key == "" {
+		return attrs
+	}
+	return append(attrs, slog.Any(key, value))
+}
+
+// AppendDuration adds all defined duration attributes
+func (k HTTPKeys) AppendDuration(attrs []slog.Attr, duration time.Duration) []slog.Attr {
+	if k.DurationNanos != "" {
+		attrs = append(attrs, slog.Duration(k.DurationNanos, duration))
+	}
+	if k.Duration != "" {
+		attrs = append(attrs, slog.String(k.Duration, duration.String()))
+	}
+	if k.DurationSeconds != "" {
+		attrs = append(attrs, slog.Float64(k.DurationSeconds, duration.Seconds()))
+	}
+	return attrs
+}
+
+// AppendURL adds path and query as separate attributes or as one when Query key is empty
+func (k HTTPKeys) AppendURL(attrs []slog.Attr, path, query string) []slog.Attr {
+	if k.Query == "" {
+		if query != "" {
+			path += "?" + query
+		}
+		return Append(attrs, k.Path, path)
+	}
+
+	attrs = Append(attrs, k.Path, path)
+	if query != "" {
+		attrs = Append(attrs, k.Query, query)
+	}
+	return attrs
+}
+
+// AppendHeaders adds headers according to RequestHeaders key
+func (k HTTPKeys) AppendHeaders(attrs []slog.Attr, headers map[string]string) []slog.Attr {
+	if k.RequestHeaders == "" || len(headers) == 0 {
+		return attrs
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if !strings.HasSuffix(k.RequestHeaders, ".") {
+		joined := make([]string, len(names))
+		for i, name := range names {
+			joined[i] = name + "=" + headers[name]
+		}
+		return append(attrs, slog.String(k.RequestHeaders, strings.Join(joined, ";")))
+	}
+
+	for _, name := range names {
+		key := name
+		if k.LowerHeaders {
+			key = strings.ToLower(name)
+		}
+		attrs = append(attrs, slog.String(k.RequestHeaders+key, headers[name]))
+	}
+	return attrs
+}
+
+// WithSpan stores span of the current request
+func WithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func GetSpan(ctx context.Context) (span Span, ok bool) {
+	if ctx == nil {
+		return span, false
+	}
+	span, ok = ctx.Value(spanKey{}).(Span)
+	return
+}
+
+// NewSpanID returns random 16 hex chars span id
+func NewSpanID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// ParseTraceParent parses W3C traceparent header: version-trace_id-parent_id-flags
+func ParseTraceParent(value string) (traceID string, parent Span, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", parent, false
+	}
+
+	for _, part := range parts[1:4] {
+		if _, err := hex.DecodeString(part); err != nil {
+			return "", parent, false
+		}
+	}
+
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", parent, false
+	}
+
+	return parts[1], Span{ID: parts[2], Flags: parts[3]}, true
+}
@@ -0,0 +1,68 @@
+package trace_logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	trace_context "github.com/rzaripov1990/trace_ctx"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	traceID, span, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || span.ID != "00f067aa0ba902b7" || span.Flags != "01" {
+		t.Fatalf("unexpected result: %s %+v %v", traceID, span, ok)
+	}
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		if _, _, ok := ParseTraceParent(value); ok {
+			t.Errorf("%q must be invalid", value)
+		}
+	}
+}
+
+func TestSchemaOutput(t *testing.T) {
+	for _, schema := range []Schema{SchemaDefault, SchemaOTel, SchemaECS} {
+		t.Run(schema.Name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			recorder = newRecorderStore(FlightRecorder{})
+			log := slog.New(&recorderHandler{
+				next:  slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError}),
+				store: recorder,
+			})
+
+			ctx := WithSpan(trace_context.SetTraceID(context.Background(), "abc"), Span{ID: "00f067aa0ba902b7", Flags: "01"})
+
+			// recorded by the trace key of the schema, it's not an attribute of the entry
+			schema.L(ctx, log).Debug("hidden", "key", "value")
+			entries := Records("abc")
+			if len(entries) != 1 || entries[0].Attrs["key"] != "value" || entries[0].Attrs[schema.TraceID] != nil {
+				t.Fatalf("unexpected records %+v", entries)
+			}
+
+			Dump(ctx, log, schema)
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("%v: %s", err, buf.String())
+			}
+			if record[schema.TraceID] != "abc" || record["msg"] != "Flight recorder" {
+				t.Errorf("unexpected dump %s", buf.String())
+			}
+			if schema.SpanID != "" && record[schema.SpanID] != "00f067aa0ba902b7" {
+				t.Errorf("expected span in dump %s", buf.String())
+			}
+			if schema.TraceID != trace_context.TraceIDKeyName && record[trace_context.TraceIDKeyName] != nil {
+				t.Errorf("unexpected default trace key %s", buf.String())
+			}
+		})
+	}
+}
@@ -14,16 +14,37 @@ var (
 	logger *slog.Logger
 )
 
-func New(logLevel string, color bool) *slog.Logger {
-	logger = slog.New(
-		slog.NewJSONHandler(
+func New(logLevel string, color bool, options ...any) *slog.Logger {
+	levels.base.Set(parseStringLevel(logLevel))
+
+	var handler slog.Handler = &levelHandler{
+		next: slog.NewJSONHandler(
 			os.Stdout,
 			&slog.HandlerOptions{
 				AddSource: false,
-				Level:     parseStringLevel(logLevel),
+				// levels are checked by levelHandler
+				Level: levelTrace,
 			},
 		),
-	)
+	}
+
+	// read all known options, ignore unknown
+	for _, option := range options {
+		switch typed := option.(type) {
+		case NamedLevels:
+			if err := SetLevels(typed.Value); err != nil {
+				panic(err)
+			}
+		case FlightRecorder:
+			recorder = newRecorderStore(typed)
+			handler = &recorderHandler{
+				next:  handler,
+				store: recorder,
+			}
+		}
+	}
+
+	logger = slog.New(handler)
 
 	return logger
 }